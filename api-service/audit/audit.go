@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"database/sql/driver"
+	"emtest/api-service/subscription"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Action is the kind of subscription change an Entry records.
+type Action string
+
+const (
+	Create  Action = "create"
+	Update  Action = "update"
+	Delete  Action = "delete"
+	Restore Action = "restore"
+	Purge   Action = "purge"
+)
+
+// Actions lists the known actions.
+var Actions = map[Action]bool{Create: true, Update: true, Delete: true, Restore: true, Purge: true}
+
+// SystemActor is recorded for changes made outside of an API request, such as
+// the scheduled purge.
+const SystemActor = "system"
+
+// Entry is an append-only record of a change to a subscription. Entries
+// outlive the subscription: purging it records one more entry.
+type Entry struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"type:uuid;not null"`
+	Action         Action    `json:"action" enums:"create,update,delete,restore,purge" example:"update"`
+	Actor          string    `json:"actor" example:"support@example.com"`
+	Changes        Changes   `json:"changes"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (Entry) TableName() string {
+	return "audit_log"
+}
+
+// Change is the old and new value of one subscription field, by its JSON name.
+// A nil value means the field was unset or the subscription did not exist.
+type Change struct {
+	Field string      `json:"field" example:"price"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Changes is stored as a JSON array.
+type Changes []Change
+
+func (c *Changes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	}
+	return fmt.Errorf("cannot scan %T into Changes", value)
+}
+
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		c = Changes{}
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Diff returns the fields that differ between before and after. A nil before
+// stands for a subscription being created, a nil after for one being purged.
+func Diff(before, after *subscription.Subscription) Changes {
+	old, updated := fields(before), fields(after)
+
+	changes := Changes{}
+	for i, field := range fieldNames {
+		if old[i] != updated[i] {
+			changes = append(changes, Change{Field: field, Old: old[i], New: updated[i]})
+		}
+	}
+	return changes
+}
+
+// fieldNames are the audited subscription fields in the order of fields.
+var fieldNames = []string{
+	"service_name", "price", "currency", "billing_period", "user_id", "start_date", "end_date", "allow_overlap", "deleted_at",
+}
+
+// fields returns the audited values of sub as comparable JSON values, all nil
+// for a nil sub.
+func fields(sub *subscription.Subscription) []interface{} {
+	values := make([]interface{}, len(fieldNames))
+	if sub == nil {
+		return values
+	}
+
+	values[0] = sub.ServiceName
+	values[1] = sub.Price
+	values[2] = sub.Currency
+	values[3] = string(sub.BillingPeriod)
+	values[4] = sub.UserId.String()
+	if !sub.StartDate.IsZero() {
+		values[5] = sub.StartDate.String()
+	}
+	if sub.EndDate != nil && !sub.EndDate.IsZero() {
+		values[6] = sub.EndDate.String()
+	}
+	values[7] = sub.AllowOverlap
+	if sub.DeletedAt != nil {
+		values[8] = sub.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx recording changes as made by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, SystemActor by default.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"emtest/api-service/subscription"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	start, _ := subscription.ParseMonth("01-2025")
+	end, _ := subscription.ParseMonth("06-2025")
+	sub := subscription.Subscription{
+		ServiceName:   "Netflix",
+		Price:         400,
+		Currency:      "RUB",
+		BillingPeriod: subscription.Monthly,
+		UserId:        uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		StartDate:     start,
+	}
+
+	created := Diff(nil, &sub)
+	assert.Len(t, created, 7)
+	assert.Equal(t, Change{Field: "service_name", Old: nil, New: "Netflix"}, created[0])
+	assert.Equal(t, Change{Field: "start_date", Old: nil, New: "01-2025"}, created[5])
+
+	updated := sub
+	updated.Price = 500
+	updated.EndDate = &end
+	updated.AllowOverlap = true
+	assert.Equal(t, Changes{
+		{Field: "price", Old: 400, New: 500},
+		{Field: "end_date", Old: nil, New: "06-2025"},
+		{Field: "allow_overlap", Old: false, New: true},
+	}, Diff(&sub, &updated))
+
+	assert.Empty(t, Diff(&sub, &sub))
+
+	deletedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted := sub
+	deleted.DeletedAt = &deletedAt
+	assert.Equal(t, Changes{{Field: "deleted_at", Old: nil, New: "2025-03-01T12:00:00Z"}}, Diff(&sub, &deleted))
+
+	assert.Len(t, Diff(&deleted, nil), 8)
+}
+
+func TestChanges_ScanValue(t *testing.T) {
+	value, err := Changes(nil).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", value)
+
+	value, err = Changes{{Field: "price", Old: 400, New: 500}}.Value()
+	assert.NoError(t, err)
+
+	var scanned Changes
+	assert.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, Changes{{Field: "price", Old: 400.0, New: 500.0}}, scanned)
+	assert.Error(t, scanned.Scan(42))
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, SystemActor, ActorFrom(ctx))
+	assert.Equal(t, "support", ActorFrom(WithActor(ctx, "support")))
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type DNS struct {
 	Host     string `mapstructure:"host"`
 	User     string `mapstructure:"user"`
@@ -9,8 +11,13 @@ type DNS struct {
 	SslMode  string `mapstructure:"sslmode"`
 }
 
+// Database selects the storage backend. ConnectTimeout is how long startup
+// waits for the database to accept connections, 30s by default.
 type Database struct {
-	DNS DNS `mapstructure:"dns"`
+	Driver         string        `mapstructure:"driver"`
+	DNS            DNS           `mapstructure:"dns"`
+	Path           string        `mapstructure:"path"`
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
 }
 
 type Server struct {
@@ -18,9 +25,83 @@ type Server struct {
 	Port int    `mapstructure:"port"`
 }
 
+type Admin struct {
+	Token string `mapstructure:"token"`
+}
+
+// Gateway authenticates the gateway in front of the service, which names the
+// user of each request in the X-Actor header. An empty Secret trusts no
+// gateway: X-Actor is then only accepted along with the admin token.
+type Gateway struct {
+	Secret string `mapstructure:"secret"`
+}
+
+// SoftDelete configures how long deleted subscriptions are kept. A zero
+// Retention keeps them forever.
+type SoftDelete struct {
+	Retention     time.Duration `mapstructure:"retention"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+// Bulk limits the number of operations of one bulk request.
+type Bulk struct {
+	MaxOperations int `mapstructure:"max_operations"`
+}
+
+// Tracing configures OpenTelemetry tracing. Exporter is otlp, stdout or file;
+// empty disables tracing. Endpoint is the host:port of the OTLP/HTTP
+// collector, the OTEL_EXPORTER_OTLP_* environment by default, and Path the
+// file spans are appended to by the file exporter.
+type Tracing struct {
+	Exporter    string `mapstructure:"exporter"`
+	Endpoint    string `mapstructure:"endpoint"`
+	Insecure    bool   `mapstructure:"insecure"`
+	Path        string `mapstructure:"path"`
+	ServiceName string `mapstructure:"service_name"`
+}
+
+// Logging configures the service logs. Format is text or json. Bodies is off,
+// truncated or full: truncated bodies are cut at MaxBodySize bytes, 1024 by
+// default. The values of the Redact JSON fields are masked wherever they
+// appear in logged bodies.
+type Logging struct {
+	Level       string          `mapstructure:"level"`
+	Format      string          `mapstructure:"format"`
+	Bodies      string          `mapstructure:"bodies"`
+	MaxBodySize int             `mapstructure:"max_body_size"`
+	Redact      []string        `mapstructure:"redact"`
+	Sampling    []RouteSampling `mapstructure:"sampling"`
+}
+
+// RouteSampling logs the given share of the successful requests of a route,
+// such as /api/v1/subscriptions/:id. Routes not listed are always logged.
+type RouteSampling struct {
+	Route string  `mapstructure:"route"`
+	Rate  float64 `mapstructure:"rate"`
+}
+
+// Health bounds the time /readyz waits for each dependency, 2s by default.
+type Health struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Metrics sets how often the monthly recurring cost gauge is recomputed, every
+// minute by default.
+type Metrics struct {
+	CostRefreshInterval time.Duration `mapstructure:"cost_refresh_interval"`
+}
+
 type Config struct {
-	Database Database `mapstructure:"database"`
-	Server   Server   `mapstructure:"server"`
+	Database   Database   `mapstructure:"database"`
+	Server     Server     `mapstructure:"server"`
+	Admin      Admin      `mapstructure:"admin"`
+	Gateway    Gateway    `mapstructure:"gateway"`
+	SoftDelete SoftDelete `mapstructure:"soft_delete"`
+	Bulk       Bulk       `mapstructure:"bulk"`
+	Tracing    Tracing    `mapstructure:"tracing"`
+	Logging    Logging    `mapstructure:"logging"`
+	Health     Health     `mapstructure:"health"`
+	Metrics    Metrics    `mapstructure:"metrics"`
 }
 
 type FConfig struct {
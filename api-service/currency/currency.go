@@ -0,0 +1,68 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Base is the currency exchange rates are quoted in and the default currency of prices.
+const Base = "RUB"
+
+var ErrMissingRate = errors.New("missing exchange rate")
+
+// Rate is the price of one unit of Currency expressed in the Base currency.
+type Rate struct {
+	Currency  string    `json:"currency" gorm:"type:varchar(3);primaryKey" validate:"required,iso4217" example:"USD"`
+	Rate      float64   `json:"rate" validate:"required,gt=0" example:"92.5"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP;autoUpdateTime"`
+}
+
+func (Rate) TableName() string {
+	return "exchange_rates"
+}
+
+// Rates maps currency codes to their rate in the Base currency.
+type Rates map[string]float64
+
+func NewRates(rates []Rate) Rates {
+	result := make(Rates, len(rates)+1)
+	for _, rate := range rates {
+		result[rate.Currency] = rate.Rate
+	}
+	result[Base] = 1
+	return result
+}
+
+// Factor returns the multiplier converting amounts in from into to.
+// An empty currency is treated as Base.
+func (r Rates) Factor(from, to string) (float64, error) {
+	if from == "" {
+		from = Base
+	}
+	if to == "" {
+		to = Base
+	}
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, ok := r[from]
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrMissingRate, from)
+	}
+	toRate, ok := r[to]
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrMissingRate, to)
+	}
+
+	return fromRate / toRate, nil
+}
+
+func (r Rates) Convert(amount float64, from, to string) (float64, error) {
+	factor, err := r.Factor(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * factor, nil
+}
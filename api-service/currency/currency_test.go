@@ -0,0 +1,43 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	rates := NewRates([]Rate{
+		{Currency: "USD", Rate: 90},
+		{Currency: "EUR", Rate: 100},
+	})
+
+	tests := []struct {
+		name    string
+		amount  float64
+		from    string
+		to      string
+		want    float64
+		wantErr bool
+	}{
+		{"Same currency", 10, "USD", "USD", 10, false},
+		{"To base", 10, "USD", "RUB", 900, false},
+		{"From base", 900, "RUB", "USD", 10, false},
+		{"Cross rate", 9, "EUR", "USD", 10, false},
+		{"Empty is base", 100, "", "EUR", 1, false},
+		{"Missing source rate", 10, "GBP", "RUB", 0, true},
+		{"Missing target rate", 10, "RUB", "GBP", 0, true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := rates.Convert(testCase.amount, testCase.from, testCase.to)
+			if testCase.wantErr {
+				assert.ErrorIs(t, err, ErrMissingRate)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, testCase.want, got, 0.0001)
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"emtest/api-service/config"
-	"emtest/api-service/subscription"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -17,28 +20,128 @@ func FormatDNS(db *config.Database) string {
 	)
 }
 
-var DB *gorm.DB
+const (
+	defaultConnectTimeout = 30 * time.Second
+	connectRetryDelay     = 250 * time.Millisecond
+	maxConnectRetryDelay  = 5 * time.Second
+)
 
-func InitDB(database config.Database) error {
-	var err error
-	DB, err = gorm.Open(
-		postgres.Open(FormatDNS(&database)),
-		&gorm.Config{},
-	)
-	if err != nil {
-		return err
+// errNoSQLSchema is returned by Open for drivers without a SQL database.
+var errNoSQLSchema = errors.New("database driver has no SQL schema")
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// FormatSQLitePath returns the SQLite connection string of the database file
+// with foreign keys enabled, so that price history is deleted with its subscription.
+func FormatSQLitePath(db *config.Database) string {
+	path := db.Path
+	if path == "" {
+		path = "subscriptions.db"
+	}
+	return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", path)
+}
+
+// Store is a storage backend for subscriptions, their audit log and exchange rates.
+type Store interface {
+	SubscriptionRepository
+	AuditRepository
+	RateRepository
+}
+
+// NewStore opens the storage backend selected by database.Driver, Postgres by
+// default. The GORM plugins are installed on SQL databases only.
+func NewStore(database config.Database, plugins ...gorm.Plugin) (Store, error) {
+	switch database.Driver {
+	case "", DriverPostgres, DriverSQLite:
+		db, err := InitDB(database)
+		if err != nil {
+			return nil, err
+		}
+		for _, plugin := range plugins {
+			if err := db.Use(plugin); err != nil {
+				return nil, err
+			}
+		}
+		return NewGormRepository(db), nil
+	case DriverMemory:
+		logrus.Warn("Using in-memory storage, data will be lost on restart")
+		return NewMemoryRepository(), nil
+	}
+	return nil, fmt.Errorf("unknown database driver %q", database.Driver)
+}
+
+// Open connects to the Postgres or SQLite database selected by database.Driver.
+func Open(database config.Database) (*gorm.DB, error) {
+	switch database.Driver {
+	case "", DriverPostgres:
+		return gorm.Open(
+			postgres.Open(FormatDNS(&database)),
+			&gorm.Config{Logger: NewQueryLogger(logrus.StandardLogger())},
+		)
+	case DriverSQLite:
+		// SQLite compares dates as text, so every timestamp is written in UTC.
+		return gorm.Open(
+			sqlite.Open(FormatSQLitePath(&database)),
+			&gorm.Config{
+				Logger:  NewQueryLogger(logrus.StandardLogger()),
+				NowFunc: func() time.Time { return time.Now().UTC() },
+			},
+		)
+	}
+	return nil, fmt.Errorf("%w: %q", errNoSQLSchema, database.Driver)
+}
+
+// Connect opens the database like Open, retrying with a growing delay while
+// the database does not accept connections yet, such as a Postgres container
+// still starting up, for up to database.ConnectTimeout.
+func Connect(ctx context.Context, database config.Database) (*gorm.DB, error) {
+	timeout := database.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	delay := connectRetryDelay
+	for {
+		db, err := Open(database)
+		if err == nil || errors.Is(err, errNoSQLSchema) {
+			return db, err
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("database is not available after %s: %w", timeout, err)
+		}
+
+		logrus.WithError(err).Warnf("Database is not available yet, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxConnectRetryDelay)
 	}
+}
 
-	// err = DB.Migrator().DropTable(&subscription.Subscription{})
-	// if err != nil {
-	// 	logrus.Printf("Failed to drop table: %v", err)
-	// }
+// InitDB connects to the database, waiting for it as Connect does, and makes
+// sure its schema is at the version of the embedded migrations. It does not
+// migrate: see Migrator.
+func InitDB(database config.Database) (*gorm.DB, error) {
+	db, err := Connect(context.Background(), database)
+	if err != nil {
+		return nil, err
+	}
 
-	err = DB.AutoMigrate(&subscription.Subscription{})
+	migrator, err := NewMigrator(db)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		return nil, err
 	}
 
 	logrus.Info("Database initiated")
-	return nil
+	return db, nil
 }
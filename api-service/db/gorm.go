@@ -0,0 +1,478 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/audit"
+	"emtest/api-service/currency"
+	"emtest/api-service/subscription"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// aggregateBatchSize is the number of subscriptions AggregateCost loads at once.
+const aggregateBatchSize = 500
+
+// GormRepository implements SubscriptionRepository and RateRepository on top of GORM.
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+// Create generates ids on the application side, as not every supported
+// database can default a column to a random UUID.
+func (r *GormRepository) Create(ctx context.Context, sub *subscription.Subscription) error {
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	sub.DeletedAt = nil
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOverlap(tx, *sub); err != nil {
+			return err
+		}
+		if err := tx.Omit("Prices").Create(sub).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&subscription.PriceChange{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			EffectiveFrom:  sub.StartDate,
+			Price:          sub.Price,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		created, err := get(tx, sub.ID, false)
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, sub.ID, audit.Create, audit.Diff(nil, &created))
+	})
+}
+
+func (r *GormRepository) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (subscription.Subscription, error) {
+	return get(r.db.WithContext(ctx), id, includeDeleted)
+}
+
+func get(tx *gorm.DB, id uuid.UUID, includeDeleted bool) (subscription.Subscription, error) {
+	var sub subscription.Subscription
+
+	if !includeDeleted {
+		tx = tx.Where("deleted_at IS NULL")
+	}
+	err := tx.Preload("Prices").First(&sub, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return sub, ErrNotFound
+	}
+	sub.Price = sub.CurrentPrice()
+	return sub, err
+}
+
+func (r *GormRepository) List(ctx context.Context, filter SubscriptionFilter, opts ListOptions) ([]subscription.Subscription, error) {
+	if !SortColumns[opts.Sort] {
+		return nil, fmt.Errorf("cannot sort by %q", opts.Sort)
+	}
+
+	comparison := ">"
+	if opts.Order == "desc" {
+		comparison = "<"
+	}
+
+	sortBy := sortExpr(opts.Sort)
+	query := applyFilter(r.db.WithContext(ctx).Model(&subscription.Subscription{}), filter)
+	if opts.After != nil {
+		query = query.Where(
+			fmt.Sprintf("(? %[1]s ? OR (? = ? AND id %[1]s ?))", comparison),
+			sortBy, opts.After.Value, sortBy, opts.After.Value, opts.After.ID,
+		)
+	}
+
+	var subs []subscription.Subscription
+
+	err := query.
+		Preload("Prices").
+		Order(clause.OrderBy{Expression: gorm.Expr(fmt.Sprintf("? %[1]s, id %[1]s", opts.Order), sortBy)}).
+		Limit(opts.Limit).
+		Find(&subs).Error
+	for i := range subs {
+		subs[i].Price = subs[i].CurrentPrice()
+		subs[i].Prices = nil
+	}
+	return subs, err
+}
+
+// sortExpr returns the expression subscriptions are listed by for the sort
+// column. The price column is only as current as the last update, so prices
+// are sorted by currentPriceExpr instead.
+func sortExpr(column string) interface{} {
+	if column == "price" {
+		return currentPriceExpr(subscription.CurrentMonth())
+	}
+	return clause.Column{Name: column}
+}
+
+// currentPriceExpr is the SQL counterpart of Subscription.PriceAt for month,
+// or for the first month of subscriptions starting later: the price of the
+// latest change effective by then, else of the earliest change.
+func currentPriceExpr(month subscription.Month) clause.Expr {
+	return gorm.Expr(`COALESCE(
+		(SELECT pc.price FROM price_changes pc WHERE pc.subscription_id = subscriptions.id
+			AND pc.effective_from <= CASE WHEN subscriptions.start_date > ? THEN subscriptions.start_date ELSE ? END
+			ORDER BY pc.effective_from DESC LIMIT 1),
+		(SELECT pc.price FROM price_changes pc WHERE pc.subscription_id = subscriptions.id
+			ORDER BY pc.effective_from LIMIT 1),
+		subscriptions.price)`, month, month)
+}
+
+func (r *GormRepository) Update(ctx context.Context, id uuid.UUID, update SubscriptionUpdate) (subscription.Subscription, error) {
+	var updated subscription.Subscription
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := get(tx, id, false)
+		if err != nil {
+			return err
+		}
+
+		merged := current
+		applyUpdate(&merged, update)
+		if err := merged.Validate(); err != nil {
+			return err
+		}
+		if update.affectsOverlap() {
+			if err := checkOverlap(tx, merged); err != nil {
+				return err
+			}
+		}
+
+		changes := priceChanges(current, update)
+		if len(changes) > 0 {
+			for i := range changes {
+				changes[i].ID = uuid.New()
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "effective_from"}},
+				DoUpdates: clause.AssignmentColumns([]string{"price"}),
+			}).Create(&changes).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if !update.IsEmpty() {
+			columns := updateColumns(update)
+			if update.Price != nil {
+				columns["price"] = currentPrice(merged, changes)
+			}
+			err := tx.Model(&subscription.Subscription{}).Where("id = ?", id).Updates(columns).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		updated, err = get(tx, id, false)
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, id, audit.Update, audit.Diff(&current, &updated))
+	})
+
+	return updated, err
+}
+
+// lockOverlap serializes the transactions writing subscriptions of the same
+// user to the same service until they commit, so that two of them cannot both
+// pass checkOverlap at READ COMMITTED. SQLite already serializes writers.
+func lockOverlap(tx *gorm.DB, sub subscription.Subscription) error {
+	if tx.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", sub.UserId.String()+"/"+sub.ServiceName).Error
+}
+
+// checkOverlap fails with ErrOverlap when sub overlaps another active
+// subscription and neither of them allows it.
+func checkOverlap(tx *gorm.DB, sub subscription.Subscription) error {
+	if sub.AllowOverlap {
+		return nil
+	}
+	if err := lockOverlap(tx, sub); err != nil {
+		return err
+	}
+
+	var others []subscription.Subscription
+
+	err := applyFilter(tx.Model(&subscription.Subscription{}), overlapFilter(sub)).
+		Where("id <> ? AND allow_overlap = ?", sub.ID, false).
+		Limit(1).
+		Find(&others).Error
+	if err != nil {
+		return err
+	}
+	if len(others) > 0 {
+		return overlapError(others[0])
+	}
+	return nil
+}
+
+// updateColumns maps the set fields of update to subscription columns.
+func updateColumns(update SubscriptionUpdate) map[string]interface{} {
+	columns := make(map[string]interface{})
+
+	if update.ServiceName != nil {
+		columns["service_name"] = *update.ServiceName
+	}
+	if update.Price != nil {
+		columns["price"] = *update.Price
+	}
+	if update.Currency != nil {
+		columns["currency"] = *update.Currency
+	}
+	if update.BillingPeriod != nil {
+		columns["billing_period"] = *update.BillingPeriod
+	}
+	if update.UserId != nil {
+		columns["user_id"] = *update.UserId
+	}
+	if update.StartDate != nil {
+		columns["start_date"] = *update.StartDate
+	}
+	if update.EndDate != nil {
+		columns["end_date"] = *update.EndDate
+	}
+	if update.AllowOverlap != nil {
+		columns["allow_overlap"] = *update.AllowOverlap
+	}
+
+	return columns
+}
+
+func (r *GormRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := get(tx, id, false)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&subscription.Subscription{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Update("deleted_at", r.db.NowFunc())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		deleted, err := get(tx, id, true)
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, id, audit.Delete, audit.Diff(&current, &deleted))
+	})
+}
+
+func (r *GormRepository) Restore(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
+	var restored subscription.Subscription
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := get(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+		if err := checkOverlap(tx, current); err != nil {
+			return err
+		}
+
+		err = tx.Model(&subscription.Subscription{}).Where("id = ?", id).Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+
+		restored, err = get(tx, id, false)
+		if err != nil {
+			return err
+		}
+		return writeAudit(tx, id, audit.Restore, audit.Diff(&current, &restored))
+	})
+
+	return restored, err
+}
+
+func (r *GormRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var expired []subscription.Subscription
+
+		err := tx.Preload("Prices").Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore.UTC()).Find(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(expired))
+		for i := range expired {
+			ids[i] = expired[i].ID
+			expired[i].Price = expired[i].CurrentPrice()
+			err := writeAudit(tx, expired[i].ID, audit.Purge, audit.Diff(&expired[i], nil))
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("subscription_id IN ?", ids).Delete(&subscription.PriceChange{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id IN ?", ids).Delete(&subscription.Subscription{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+
+	return purged, err
+}
+
+func (r *GormRepository) AggregateCost(ctx context.Context, filter SubscriptionFilter, fn func(subscription.Subscription) error) error {
+	var batch []subscription.Subscription
+
+	query := applyFilter(r.db.WithContext(ctx).Model(&subscription.Subscription{}).Preload("Prices"), filter)
+	result := query.FindInBatches(&batch, aggregateBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, sub := range batch {
+			sub.Price = sub.CurrentPrice()
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return result.Error
+}
+
+func (r *GormRepository) CountByService(ctx context.Context, filter SubscriptionFilter) (map[string]int64, error) {
+	var rows []struct {
+		ServiceName string
+		Count       int64
+	}
+
+	err := applyFilter(r.db.WithContext(ctx).Model(&subscription.Subscription{}), filter).
+		Select("service_name, COUNT(*) AS count").
+		Group("service_name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ServiceName] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *GormRepository) Transaction(ctx context.Context, fn func(tx SubscriptionRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRepository(tx))
+	})
+}
+
+// writeAudit appends an audit entry for the subscription within tx, made by
+// the actor of the transaction context. Updates that change nothing are not
+// recorded.
+func writeAudit(tx *gorm.DB, id uuid.UUID, action audit.Action, changes audit.Changes) error {
+	if action == audit.Update && len(changes) == 0 {
+		return nil
+	}
+
+	return tx.Create(&audit.Entry{
+		ID:             uuid.New(),
+		SubscriptionID: id,
+		Action:         action,
+		Actor:          audit.ActorFrom(tx.Statement.Context),
+		Changes:        changes,
+	}).Error
+}
+
+func (r *GormRepository) ListAudit(ctx context.Context, filter AuditFilter, limit int, after *ListCursor) ([]audit.Entry, error) {
+	query := r.db.WithContext(ctx).Model(&audit.Entry{})
+
+	if filter.SubscriptionID != nil {
+		query = query.Where("subscription_id = ?", *filter.SubscriptionID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until.UTC())
+	}
+	if after != nil {
+		query = query.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.Value, after.Value, after.ID)
+	}
+
+	var entries []audit.Entry
+
+	err := query.Order("created_at").Order("id").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *GormRepository) ListRates(ctx context.Context) ([]currency.Rate, error) {
+	var rates []currency.Rate
+
+	err := r.db.WithContext(ctx).Order("currency").Find(&rates).Error
+	return rates, err
+}
+
+func (r *GormRepository) SetRate(ctx context.Context, rate *currency.Rate) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate).Error
+}
+
+func (r *GormRepository) DeleteRate(ctx context.Context, code string) error {
+	result := r.db.WithContext(ctx).Delete(&currency.Rate{}, "currency = ?", code)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// applyFilter narrows query down by filter.
+func applyFilter(query *gorm.DB, filter SubscriptionFilter) *gorm.DB {
+	if filter.UserId != nil {
+		query = query.Where("user_id = ?", *filter.UserId)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if !filter.ActiveFrom.IsZero() {
+		query = query.Where("(end_date IS NULL OR end_date >= ?)", filter.ActiveFrom)
+	}
+	if !filter.ActiveTo.IsZero() {
+		query = query.Where("start_date <= ?", filter.ActiveTo)
+	}
+	if !filter.IncludeDeleted {
+		query = query.Where("deleted_at IS NULL")
+	}
+	return query
+}
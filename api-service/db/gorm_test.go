@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/currency"
+	"emtest/api-service/subscription"
+	"fmt"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// TestGormRepository runs the Store checks against Postgres 15 started with
+// dockertest. It is skipped when Docker is not available.
+func TestGormRepository(t *testing.T) {
+	testDB := startPostgres(t)
+
+	migrator, err := NewMigrator(testDB)
+	if err != nil {
+		t.Fatalf("Could not load migrations: %s", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Could not migrate database: %s", err)
+	}
+
+	testStore(t, func(t *testing.T) Store {
+		testDB.Where("1 = 1").Delete(&subscription.PriceChange{})
+		testDB.Where("1 = 1").Delete(&subscription.Subscription{})
+		testDB.Where("1 = 1").Delete(&currency.Rate{})
+		return NewGormRepository(testDB)
+	})
+}
+
+// TestPostgresMonthDatesMigration checks that the conversion of MM-YYYY
+// strings to dates stops on malformed values and names the subscription.
+func TestPostgresMonthDatesMigration(t *testing.T) {
+	ctx := context.Background()
+	testDB := startPostgres(t)
+
+	migrator, err := NewMigrator(testDB)
+	if err != nil {
+		t.Fatalf("Could not load migrations: %s", err)
+	}
+	initial := &Migrator{db: testDB, migrations: migrator.migrations[:1]}
+	if _, err := initial.Up(ctx); err != nil {
+		t.Fatalf("Could not create the initial schema: %s", err)
+	}
+
+	const malformed = "6e2f1c1a-3a6b-4f0e-9a57-6b0a3e0d8c11"
+	err = testDB.Exec(`INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date) VALUES
+		('9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d', 'Netflix', 400, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '01-2024', ''),
+		(?, 'Spotify', 200, '60601fee-2bf1-4721-ae6f-7636e79a0cba', '02-2024', '2024-12')`, malformed).Error
+	assert.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "end_date of subscriptions "+malformed)
+	}
+	version, err := migrator.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	assert.NoError(t, testDB.Exec("UPDATE subscriptions SET end_date = '12-2024' WHERE id = ?", malformed).Error)
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Check(ctx))
+}
+
+// startPostgres starts Postgres 15 with dockertest, removed when the test
+// ends. The test is skipped when Docker is not available.
+func startPostgres(t *testing.T) *gorm.DB {
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		t.Skipf("Docker is not available: %s", err)
+	}
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "15",
+		Env: []string{
+			"POSTGRES_PASSWORD=secret",
+			"POSTGRES_USER=test",
+			"POSTGRES_DB=test_db",
+			"listen_address = '*'",
+		},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		t.Fatalf("Couldn't start resource: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Purge(resource); err != nil {
+			t.Errorf("Couldn't purge resource: %s", err)
+		}
+	})
+
+	var testDB *gorm.DB
+	dns := fmt.Sprintf(
+		"host=localhost user=test password=secret dbname=test_db port=%s sslmode=disable TimeZone=UTC",
+		resource.GetPort("5432/tcp"),
+	)
+
+	if err := pool.Retry(func() error {
+		logrus.Info("Attempt to connect to db...")
+		var err error
+		testDB, err = gorm.Open(postgres.Open(dns), &gorm.Config{})
+		if err != nil {
+			return err
+		}
+
+		sqlDB, err := testDB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Ping()
+	}); err != nil {
+		t.Fatalf("Failed to connect to database: %s", err)
+	}
+
+	return testDB
+}
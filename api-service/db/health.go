@@ -0,0 +1,29 @@
+package db
+
+import "context"
+
+// HealthChecker is implemented by the stores backed by a database server, so
+// that readiness probes can check it.
+type HealthChecker interface {
+	// Ping checks that the database accepts connections.
+	Ping(ctx context.Context) error
+	// CheckSchema returns ErrSchemaMismatch unless the schema is at the
+	// version of the embedded migrations.
+	CheckSchema(ctx context.Context) error
+}
+
+func (r *GormRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (r *GormRepository) CheckSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(r.db)
+	if err != nil {
+		return err
+	}
+	return migrator.Check(ctx)
+}
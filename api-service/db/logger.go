@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/requestid"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration from which queries are logged as slow.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// queryLogger writes the GORM logs through logrus, tagged with the id of the
+// request that made the query. Failed queries are logged as errors, slow ones
+// as warnings and the others at debug level. Queries are logged with their
+// placeholders rather than the values bound to them.
+type queryLogger struct {
+	logger *logrus.Logger
+	level  gormlogger.LogLevel
+}
+
+// NewQueryLogger returns a GORM logger writing to logger.
+func NewQueryLogger(logger *logrus.Logger) gormlogger.Interface {
+	return queryLogger{logger: logger, level: gormlogger.Info}
+}
+
+func (l queryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	l.level = level
+	return l
+}
+
+func (l queryLogger) entry(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(l.logger).WithContext(ctx)
+	if id := requestid.From(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+	return entry
+}
+
+func (l queryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.entry(ctx).Infof(msg, args...)
+	}
+}
+
+func (l queryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.entry(ctx).Warnf(msg, args...)
+	}
+}
+
+func (l queryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.entry(ctx).Errorf(msg, args...)
+	}
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	duration := time.Since(begin)
+	level := logrus.DebugLevel
+	message := "Query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		level, message = logrus.ErrorLevel, "Query failed"
+	case duration > slowQueryThreshold && l.level >= gormlogger.Warn:
+		level, message = logrus.WarnLevel, fmt.Sprintf("Slow query, over %s", slowQueryThreshold)
+	case l.level < gormlogger.Info:
+		return
+	}
+	if !l.logger.IsLevelEnabled(level) {
+		return
+	}
+
+	sql, rows := fc()
+	entry := l.entry(ctx).WithFields(logrus.Fields{
+		"sql":         sql,
+		"rows":        rows,
+		"duration_ms": float64(duration.Microseconds()) / 1000,
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Log(level, message)
+}
+
+// ParamsFilter leaves the bound values out of logged queries, as they hold
+// user data.
+func (l queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
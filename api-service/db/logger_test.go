@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/requestid"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestQueryLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	queryLog := NewQueryLogger(logger)
+
+	ctx := requestid.With(context.Background(), "request-1")
+	query := func() (string, int64) { return "SELECT * FROM subscriptions WHERE id = ?", 1 }
+
+	queryLog.Trace(ctx, time.Now(), query, nil)
+	queryLog.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	queryLog.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	queryLog.Trace(context.Background(), time.Now(), query, errors.New("connection refused"))
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 4)
+	for _, entry := range entries[:3] {
+		assert.Equal(t, "request-1", entry.Data["request_id"])
+		assert.Equal(t, "SELECT * FROM subscriptions WHERE id = ?", entry.Data["sql"])
+	}
+	assert.Equal(t, logrus.DebugLevel, entries[0].Level)
+	assert.Equal(t, logrus.DebugLevel, entries[1].Level)
+	assert.Equal(t, logrus.WarnLevel, entries[2].Level)
+	assert.Equal(t, logrus.ErrorLevel, entries[3].Level)
+	assert.NotContains(t, entries[3].Data, "request_id")
+
+	hook.Reset()
+	queryLog.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), query, errors.New("connection refused"))
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestQueryLogger_LeavesValuesOut(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	db := openSQLite(t)
+	db.Logger = NewQueryLogger(logger)
+
+	var count int64
+	require.NoError(t, db.WithContext(requestid.With(context.Background(), "request-2")).
+		Table("subscriptions").Where("service_name = ?", "Secret service").Count(&count).Error)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "request-2", entry.Data["request_id"])
+	assert.Contains(t, entry.Data["sql"], "service_name = ?")
+	assert.NotContains(t, entry.Data["sql"], "Secret service")
+}
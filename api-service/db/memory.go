@@ -0,0 +1,488 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/audit"
+	"emtest/api-service/currency"
+	"emtest/api-service/subscription"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryRepository implements SubscriptionRepository and RateRepository in
+// memory. It follows the semantics of GormRepository on Postgres and is meant
+// for tests and demos: nothing survives a restart.
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[uuid.UUID]subscription.Subscription
+	audit         []audit.Entry
+	rates         map[string]currency.Rate
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		subscriptions: make(map[uuid.UUID]subscription.Subscription),
+		rates:         make(map[string]currency.Rate),
+	}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, sub *subscription.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	if _, exists := r.subscriptions[sub.ID]; exists {
+		return fmt.Errorf("subscription %s already exists", sub.ID)
+	}
+	if err := r.checkOverlap(*sub); err != nil {
+		return err
+	}
+	sub.DeletedAt = nil
+	if sub.Currency == "" {
+		sub.Currency = currency.Base
+	}
+	if sub.BillingPeriod == "" {
+		sub.BillingPeriod = subscription.Monthly
+	}
+
+	now := time.Now()
+	sub.CreatedAt, sub.UpdatedAt = now, now
+
+	stored := *sub
+	stored.Prices = []subscription.PriceChange{{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		EffectiveFrom:  sub.StartDate,
+		Price:          sub.Price,
+		CreatedAt:      now,
+	}}
+	r.subscriptions[sub.ID] = stored
+	r.writeAudit(ctx, sub.ID, audit.Create, audit.Diff(nil, &stored))
+
+	return nil
+}
+
+func (r *MemoryRepository) Get(_ context.Context, id uuid.UUID, includeDeleted bool) (subscription.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, exists := r.subscriptions[id]
+	if !exists || (sub.DeletedAt != nil && !includeDeleted) {
+		return subscription.Subscription{}, ErrNotFound
+	}
+	return clone(sub), nil
+}
+
+func (r *MemoryRepository) List(_ context.Context, filter SubscriptionFilter, opts ListOptions) ([]subscription.Subscription, error) {
+	if !SortColumns[opts.Sort] {
+		return nil, fmt.Errorf("cannot sort by %q", opts.Sort)
+	}
+
+	subs := r.find(filter)
+	for i := range subs {
+		subs[i].Prices = nil
+	}
+
+	desc := opts.Order == "desc"
+	sort.Slice(subs, func(i, j int) bool {
+		return compareSubscriptions(subs[i], subs[j], opts.Sort, desc) < 0
+	})
+
+	if opts.After != nil {
+		start := sort.Search(len(subs), func(i int) bool {
+			return compareToCursor(subs[i], opts.Sort, *opts.After, desc) > 0
+		})
+		subs = subs[start:]
+	}
+
+	if opts.Limit > 0 && len(subs) > opts.Limit {
+		subs = subs[:opts.Limit]
+	}
+	return subs, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id uuid.UUID, update SubscriptionUpdate) (subscription.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, exists := r.subscriptions[id]
+	if !exists || sub.DeletedAt != nil {
+		return subscription.Subscription{}, ErrNotFound
+	}
+	sub = clone(sub)
+	current := sub
+
+	merged := sub
+	applyUpdate(&merged, update)
+	if err := merged.Validate(); err != nil {
+		return subscription.Subscription{}, err
+	}
+	if update.affectsOverlap() {
+		if err := r.checkOverlap(merged); err != nil {
+			return subscription.Subscription{}, err
+		}
+	}
+
+	now := time.Now()
+	for _, change := range priceChanges(sub, update) {
+		sub.Prices = upsertPriceChange(sub.Prices, change, now)
+	}
+
+	if !update.IsEmpty() {
+		applyUpdate(&sub, update)
+		if update.Price != nil {
+			sub.Price = currentPrice(sub, nil)
+		}
+		sub.UpdatedAt = now
+	}
+	r.subscriptions[id] = sub
+	r.writeAudit(ctx, id, audit.Update, audit.Diff(&current, &sub))
+
+	return clone(sub), nil
+}
+
+// checkOverlap fails with ErrOverlap when sub overlaps another active
+// subscription and neither of them allows it. The caller must hold the lock.
+func (r *MemoryRepository) checkOverlap(sub subscription.Subscription) error {
+	if sub.AllowOverlap {
+		return nil
+	}
+
+	filter := overlapFilter(sub)
+	for _, other := range r.subscriptions {
+		if other.ID != sub.ID && !other.AllowOverlap && matches(other, filter) {
+			return overlapError(other)
+		}
+	}
+	return nil
+}
+
+// upsertPriceChange adds change to prices, replacing the price of an existing
+// change effective from the same month.
+func upsertPriceChange(prices []subscription.PriceChange, change subscription.PriceChange, now time.Time) []subscription.PriceChange {
+	for i := range prices {
+		if prices[i].EffectiveFrom == change.EffectiveFrom {
+			prices[i].Price = change.Price
+			return prices
+		}
+	}
+
+	change.ID = uuid.New()
+	change.CreatedAt = now
+	return append(prices, change)
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.subscriptions[id]
+	if !exists || current.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	sub := current
+	deletedAt := time.Now()
+	sub.DeletedAt = &deletedAt
+	r.subscriptions[id] = sub
+	r.writeAudit(ctx, id, audit.Delete, audit.Diff(&current, &sub))
+	return nil
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.subscriptions[id]
+	if !exists {
+		return subscription.Subscription{}, ErrNotFound
+	}
+	if current.DeletedAt == nil {
+		return subscription.Subscription{}, ErrNotDeleted
+	}
+	if err := r.checkOverlap(current); err != nil {
+		return subscription.Subscription{}, err
+	}
+
+	sub := current
+	sub.DeletedAt = nil
+	r.subscriptions[id] = sub
+	r.writeAudit(ctx, id, audit.Restore, audit.Diff(&current, &sub))
+	return clone(sub), nil
+}
+
+func (r *MemoryRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, sub := range r.subscriptions {
+		if sub.DeletedAt != nil && sub.DeletedAt.Before(deletedBefore) {
+			delete(r.subscriptions, id)
+			sub = clone(sub)
+			r.writeAudit(ctx, id, audit.Purge, audit.Diff(&sub, nil))
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Transaction runs fn on a copy of the subscriptions and the audit log and
+// keeps the copy when fn succeeds. Other calls wait until fn returns.
+func (r *MemoryRepository) Transaction(_ context.Context, fn func(tx SubscriptionRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &MemoryRepository{
+		subscriptions: make(map[uuid.UUID]subscription.Subscription, len(r.subscriptions)),
+		audit:         append([]audit.Entry(nil), r.audit...),
+		rates:         r.rates,
+	}
+	for id, sub := range r.subscriptions {
+		tx.subscriptions[id] = clone(sub)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	r.subscriptions, r.audit = tx.subscriptions, tx.audit
+	return nil
+}
+
+// writeAudit appends an audit entry made by the actor of ctx. Updates that
+// change nothing are not recorded. The caller must hold the write lock.
+func (r *MemoryRepository) writeAudit(ctx context.Context, id uuid.UUID, action audit.Action, changes audit.Changes) {
+	if action == audit.Update && len(changes) == 0 {
+		return
+	}
+
+	r.audit = append(r.audit, audit.Entry{
+		ID:             uuid.New(),
+		SubscriptionID: id,
+		Action:         action,
+		Actor:          audit.ActorFrom(ctx),
+		Changes:        changes,
+		CreatedAt:      time.Now(),
+	})
+}
+
+// ListAudit returns entries in the order they were written, which is the
+// creation time order.
+func (r *MemoryRepository) ListAudit(_ context.Context, filter AuditFilter, limit int, after *ListCursor) ([]audit.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start := 0
+	if after != nil {
+		for i, entry := range r.audit {
+			if entry.ID == after.ID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	entries := []audit.Entry{}
+	for _, entry := range r.audit[start:] {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		if auditMatches(entry, filter) {
+			entry.Changes = append(audit.Changes(nil), entry.Changes...)
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func auditMatches(entry audit.Entry, filter AuditFilter) bool {
+	if filter.SubscriptionID != nil && entry.SubscriptionID != *filter.SubscriptionID {
+		return false
+	}
+	if filter.Actor != "" && entry.Actor != filter.Actor {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+	if !filter.Since.IsZero() && entry.CreatedAt.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !entry.CreatedAt.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+func (r *MemoryRepository) AggregateCost(_ context.Context, filter SubscriptionFilter, fn func(subscription.Subscription) error) error {
+	subs := r.find(filter)
+	sort.Slice(subs, func(i, j int) bool {
+		return compareSubscriptions(subs[i], subs[j], "created_at", false) < 0
+	})
+
+	for _, sub := range subs {
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CountByService(_ context.Context, filter SubscriptionFilter) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, sub := range r.subscriptions {
+		if matches(sub, filter) {
+			counts[sub.ServiceName]++
+		}
+	}
+	return counts, nil
+}
+
+// find returns copies of the subscriptions matching filter.
+func (r *MemoryRepository) find(filter SubscriptionFilter) []subscription.Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := []subscription.Subscription{}
+	for _, sub := range r.subscriptions {
+		if matches(sub, filter) {
+			subs = append(subs, clone(sub))
+		}
+	}
+	return subs
+}
+
+func (r *MemoryRepository) ListRates(_ context.Context) ([]currency.Rate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rates := make([]currency.Rate, 0, len(r.rates))
+	for _, rate := range r.rates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+	return rates, nil
+}
+
+func (r *MemoryRepository) SetRate(_ context.Context, rate *currency.Rate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rate.UpdatedAt = time.Now()
+	r.rates[rate.Currency] = *rate
+	return nil
+}
+
+func (r *MemoryRepository) DeleteRate(_ context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.rates[code]; !exists {
+		return ErrNotFound
+	}
+	delete(r.rates, code)
+	return nil
+}
+
+// matches is the in-memory counterpart of applyFilter.
+func matches(sub subscription.Subscription, filter SubscriptionFilter) bool {
+	if filter.UserId != nil && sub.UserId != *filter.UserId {
+		return false
+	}
+	if filter.ServiceName != "" && sub.ServiceName != filter.ServiceName {
+		return false
+	}
+	if !filter.ActiveFrom.IsZero() && sub.EndDate != nil && !sub.EndDate.IsZero() && sub.EndDate.Before(filter.ActiveFrom) {
+		return false
+	}
+	if !filter.ActiveTo.IsZero() && sub.StartDate.After(filter.ActiveTo) {
+		return false
+	}
+	if !filter.IncludeDeleted && sub.DeletedAt != nil {
+		return false
+	}
+	return true
+}
+
+// compareSubscriptions orders subscriptions by the column and then the id, the
+// same way List orders rows in SQL.
+func compareSubscriptions(a, b subscription.Subscription, column string, desc bool) int {
+	return compareToCursor(a, column, ListCursor{Value: sortValue(b, column), ID: b.ID}, desc)
+}
+
+// compareToCursor reports whether sub comes before (-1), at (0) or after (1)
+// the cursor in the listing order.
+func compareToCursor(sub subscription.Subscription, column string, cursor ListCursor, desc bool) int {
+	result := compareValues(sortValue(sub, column), cursor.Value)
+	if result == 0 {
+		result = strings.Compare(sub.ID.String(), cursor.ID.String())
+	}
+	if desc {
+		return -result
+	}
+	return result
+}
+
+func sortValue(sub subscription.Subscription, column string) interface{} {
+	switch column {
+	case "price":
+		return sub.Price
+	case "start_date":
+		return sub.StartDate
+	default:
+		return sub.CreatedAt
+	}
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		b := b.(int)
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+	case subscription.Month:
+		b := b.(subscription.Month)
+		if a.Before(b) {
+			return -1
+		}
+		if a.After(b) {
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// clone copies sub so that callers cannot change the stored subscription,
+// with the price of its history in effect in the current month.
+func clone(sub subscription.Subscription) subscription.Subscription {
+	if sub.EndDate != nil {
+		endDate := *sub.EndDate
+		sub.EndDate = &endDate
+	}
+	if sub.DeletedAt != nil {
+		deletedAt := *sub.DeletedAt
+		sub.DeletedAt = &deletedAt
+	}
+	sub.Prices = append([]subscription.PriceChange(nil), sub.Prices...)
+	sub.Price = sub.CurrentPrice()
+	return sub
+}
@@ -0,0 +1,9 @@
+package db
+
+import "testing"
+
+func TestMemoryRepository(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemoryRepository()
+	})
+}
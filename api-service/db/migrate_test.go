@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/config"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	for _, driver := range []string{DriverPostgres, DriverSQLite} {
+		migrations, err := loadMigrations(driver)
+		assert.NoError(t, err, driver)
+		assert.NotEmpty(t, migrations, driver)
+
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version, driver)
+			assert.NotEmpty(t, migration.Up, driver)
+			assert.NotEmpty(t, migration.Down, driver)
+		}
+	}
+
+	_, err := loadMigrations(DriverMemory)
+	assert.Error(t, err)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Check(ctx))
+
+	version, err := migrator.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, 1)
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaMismatch)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	rolledBack, err = migrator.Down(ctx, migrator.Latest())
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, migrator.Latest()-1)
+	assert.False(t, db.Migrator().HasTable("subscriptions"))
+
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, migrator.Latest())
+	assert.NoError(t, migrator.Check(ctx))
+
+	assert.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)", migrator.Latest()+1).Error)
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaMismatch)
+}
+
+func TestMigrator_EmptyDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(config.Database{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	assert.NoError(t, err)
+
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+
+	version, err := migrator.Version(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, migrator.Latest())
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaMismatch)
+	assert.False(t, db.Migrator().HasTable("schema_migrations"), "checks must not create the table")
+
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Check(ctx))
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    service_name text,
+    price bigint,
+    user_id text,
+    start_date text,
+    end_date text,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
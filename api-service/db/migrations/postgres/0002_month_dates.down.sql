@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE text USING to_char(start_date, 'MM-YYYY'),
+    ALTER COLUMN end_date TYPE text USING to_char(end_date, 'MM-YYYY');
@@ -0,0 +1,42 @@
+-- start_date and end_date used to be "MM-YYYY" strings. Databases created by
+-- AutoMigrate may already have date columns, so only text columns are converted.
+-- Values that are not MM-YYYY would make to_date fail or guess a date, so the
+-- migration stops and names the subscriptions to fix first.
+DO $$
+DECLARE
+    malformed text;
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'subscriptions' AND column_name = 'start_date') = 'text' THEN
+        SELECT string_agg(format('%s (%L)', id, start_date), ', ') INTO malformed
+        FROM (
+            SELECT id, start_date FROM subscriptions
+            WHERE NULLIF(start_date, '') !~ '^(0[1-9]|1[0-2])-[0-9]{4}$'
+            ORDER BY id LIMIT 20
+        ) bad;
+        IF malformed IS NOT NULL THEN
+            RAISE EXCEPTION 'start_date of subscriptions % is not in MM-YYYY format', malformed
+                USING HINT = 'Fix or clear these values, then run migrate up again.';
+        END IF;
+
+        ALTER TABLE subscriptions
+            ALTER COLUMN start_date TYPE date USING to_date(NULLIF(start_date, ''), 'MM-YYYY');
+    END IF;
+
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'subscriptions' AND column_name = 'end_date') = 'text' THEN
+        SELECT string_agg(format('%s (%L)', id, end_date), ', ') INTO malformed
+        FROM (
+            SELECT id, end_date FROM subscriptions
+            WHERE NULLIF(end_date, '') !~ '^(0[1-9]|1[0-2])-[0-9]{4}$'
+            ORDER BY id LIMIT 20
+        ) bad;
+        IF malformed IS NOT NULL THEN
+            RAISE EXCEPTION 'end_date of subscriptions % is not in MM-YYYY format', malformed
+                USING HINT = 'Fix or clear these values, then run migrate up again.';
+        END IF;
+
+        ALTER TABLE subscriptions
+            ALTER COLUMN end_date TYPE date USING to_date(NULLIF(end_date, ''), 'MM-YYYY');
+    END IF;
+END $$;
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS currency varchar(3) DEFAULT 'RUB',
+    ADD COLUMN IF NOT EXISTS billing_period text DEFAULT 'monthly';
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency varchar(3) PRIMARY KEY,
+    rate decimal,
+    updated_at timestamptz
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS price_changes (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    subscription_id uuid NOT NULL,
+    effective_from date NOT NULL,
+    price bigint,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_subscriptions_prices FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_changes_subscription_month
+    ON price_changes (subscription_id, effective_from);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id uuid PRIMARY KEY,
+    subscription_id uuid NOT NULL,
+    action text NOT NULL,
+    actor text NOT NULL,
+    changes jsonb NOT NULL DEFAULT '[]',
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_subscription ON audit_log (subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at, id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS allow_overlap boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_service ON subscriptions (user_id, service_name);
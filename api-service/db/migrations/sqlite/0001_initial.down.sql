@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS price_changes;
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id uuid PRIMARY KEY,
+    service_name text,
+    price integer,
+    currency varchar(3) DEFAULT 'RUB',
+    billing_period text DEFAULT 'monthly',
+    user_id text,
+    start_date date,
+    end_date date,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS price_changes (
+    id uuid PRIMARY KEY,
+    subscription_id uuid NOT NULL,
+    effective_from date NOT NULL,
+    price integer,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_subscriptions_prices FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_changes_subscription_month
+    ON price_changes (subscription_id, effective_from);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency varchar(3) PRIMARY KEY,
+    rate real,
+    updated_at datetime
+);
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions ADD COLUMN deleted_at datetime;
+
+CREATE INDEX idx_subscriptions_deleted_at ON subscriptions (deleted_at);
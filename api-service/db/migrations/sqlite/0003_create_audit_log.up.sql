@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id uuid PRIMARY KEY,
+    subscription_id uuid NOT NULL,
+    action text NOT NULL,
+    actor text NOT NULL,
+    changes text NOT NULL DEFAULT '[]',
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_subscription ON audit_log (subscription_id, created_at);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at, id);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_user_service;
+
+ALTER TABLE subscriptions DROP COLUMN allow_overlap;
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/config"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPurgeInterval is used when soft_delete.purge_interval is not set.
+const defaultPurgeInterval = time.Hour
+
+// RunPurge permanently removes subscriptions deleted longer than the retention
+// period ago, once right away and then every purge interval, until ctx is
+// done. It returns at once when retention is disabled.
+func RunPurge(ctx context.Context, subscriptions SubscriptionRepository, cfg config.SoftDelete) {
+	if cfg.Retention <= 0 {
+		logrus.Info("Soft-deleted subscriptions are kept forever: soft_delete.retention is not set")
+		return
+	}
+
+	interval := cfg.PurgeInterval
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := subscriptions.Purge(ctx, time.Now().Add(-cfg.Retention))
+		if err != nil {
+			logrus.Errorf("Failed to purge deleted subscriptions: %s", err)
+		} else if purged > 0 {
+			logrus.Infof("Purged %d subscriptions deleted more than %s ago", purged, cfg.Retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/config"
+	"emtest/api-service/subscription"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPurge(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRepository()
+
+	jan, _ := subscription.ParseMonth("01-2025")
+	sub := subscription.Subscription{ServiceName: "Netflix", Price: 100, StartDate: jan}
+	assert.NoError(t, store.Create(ctx, &sub))
+	assert.NoError(t, store.Delete(ctx, sub.ID))
+
+	// Disabled retention leaves deleted subscriptions alone.
+	RunPurge(ctx, store, config.SoftDelete{})
+	_, err := store.Get(ctx, sub.ID, true)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		RunPurge(ctx, store, config.SoftDelete{Retention: time.Nanosecond, PurgeInterval: time.Millisecond})
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := store.Get(context.Background(), sub.ID, true)
+		return err == ErrNotFound
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPurge did not stop after the context was cancelled")
+	}
+}
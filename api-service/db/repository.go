@@ -0,0 +1,250 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/audit"
+	"emtest/api-service/currency"
+	"emtest/api-service/subscription"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("record not found")
+	// ErrNotDeleted is returned when restoring a subscription that is not deleted.
+	ErrNotDeleted = errors.New("record is not deleted")
+	// ErrOverlap is returned when a subscription would be active in the same
+	// months as another active subscription of the user to the same service
+	// and neither of them has AllowOverlap set.
+	ErrOverlap = errors.New("overlapping subscription")
+)
+
+// SortColumns lists the columns subscriptions can be listed by.
+var SortColumns = map[string]bool{
+	"created_at": true,
+	"price":      true,
+	"start_date": true,
+}
+
+// SubscriptionRepository stores subscriptions along with their price history.
+// Every change is recorded in the audit log in the same transaction, as made
+// by the actor of the context (see audit.WithActor).
+type SubscriptionRepository interface {
+	// Create stores sub and records its price as effective from the start date.
+	// It fails with ErrOverlap when sub overlaps another subscription.
+	Create(ctx context.Context, sub *subscription.Subscription) error
+	// Get returns the subscription with its price history loaded. Soft-deleted
+	// subscriptions are only returned when includeDeleted is set.
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (subscription.Subscription, error)
+	// List returns a page of subscriptions matching filter.
+	List(ctx context.Context, filter SubscriptionFilter, opts ListOptions) ([]subscription.Subscription, error)
+	// Update applies update and returns the updated subscription. It fails with
+	// subscription.ValidationErrors when the updated subscription is invalid,
+	// such as ending before it starts, and with ErrOverlap when it overlaps
+	// another subscription.
+	Update(ctx context.Context, id uuid.UUID, update SubscriptionUpdate) (subscription.Subscription, error)
+	// Delete soft-deletes the subscription: it is kept with DeletedAt set
+	// until Purge removes it.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore clears DeletedAt of a soft-deleted subscription. It fails with
+	// ErrOverlap when the subscription overlaps one created in the meantime.
+	Restore(ctx context.Context, id uuid.UUID) (subscription.Subscription, error)
+	// Purge permanently removes subscriptions deleted before the given time
+	// along with their price history and returns how many were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// AggregateCost calls fn for every subscription matching filter, with its
+	// price history loaded, so that the caller can sum up their costs. It stops
+	// at the first error returned by fn.
+	AggregateCost(ctx context.Context, filter SubscriptionFilter, fn func(subscription.Subscription) error) error
+	// CountByService returns the number of subscriptions matching filter by
+	// service name.
+	CountByService(ctx context.Context, filter SubscriptionFilter) (map[string]int64, error)
+	// Transaction calls fn with a repository whose changes are committed
+	// together when fn returns nil and discarded when it returns an error.
+	Transaction(ctx context.Context, fn func(tx SubscriptionRepository) error) error
+}
+
+// RateRepository stores exchange rates to the base currency.
+type RateRepository interface {
+	ListRates(ctx context.Context) ([]currency.Rate, error)
+	// SetRate creates or replaces the rate of rate.Currency.
+	SetRate(ctx context.Context, rate *currency.Rate) error
+	DeleteRate(ctx context.Context, code string) error
+}
+
+// AuditRepository reads the audit log written by SubscriptionRepository.
+type AuditRepository interface {
+	// ListAudit returns entries matching filter, oldest first. After continues
+	// the listing from a previously returned entry by its creation time and id.
+	ListAudit(ctx context.Context, filter AuditFilter, limit int, after *ListCursor) ([]audit.Entry, error)
+}
+
+// AuditFilter narrows audit entries down. Since and Until bound the creation
+// time, Until excluded; zero fields match everything.
+type AuditFilter struct {
+	SubscriptionID *uuid.UUID
+	Actor          string
+	Action         audit.Action
+	Since          time.Time
+	Until          time.Time
+}
+
+// SubscriptionFilter narrows subscriptions down. ActiveFrom and ActiveTo describe
+// a month window and keep subscriptions active at least one month in it; a zero
+// bound leaves the window open on that side. Soft-deleted subscriptions are
+// left out unless IncludeDeleted is set.
+type SubscriptionFilter struct {
+	UserId         *uuid.UUID
+	ServiceName    string
+	ActiveFrom     subscription.Month
+	ActiveTo       subscription.Month
+	IncludeDeleted bool
+}
+
+// ListOptions orders subscriptions by Sort, one of SortColumns, and the id.
+// After continues the listing from a previously returned subscription.
+type ListOptions struct {
+	Limit int
+	Sort  string
+	Order string
+	After *ListCursor
+}
+
+// ListCursor points at a listed subscription by its Sort column value and id.
+type ListCursor struct {
+	Value interface{}
+	ID    uuid.UUID
+}
+
+// SubscriptionUpdate holds the fields to change; nil fields are left as they
+// are and a zero EndDate clears the end date. A new Price is recorded in the
+// price history from PriceEffectiveFrom, the current month by default.
+// Subscriptions are read with the price of the history in effect in the
+// current month, so a price set from a future month shows from that month on.
+type SubscriptionUpdate struct {
+	ServiceName        *string
+	Price              *int
+	PriceEffectiveFrom subscription.Month
+	Currency           *string
+	BillingPeriod      *subscription.BillingPeriod
+	UserId             *uuid.UUID
+	StartDate          *subscription.Month
+	EndDate            *subscription.Month
+	AllowOverlap       *bool
+}
+
+// IsEmpty reports whether the update changes nothing.
+func (u SubscriptionUpdate) IsEmpty() bool {
+	return u.ServiceName == nil && u.Price == nil && u.Currency == nil && u.BillingPeriod == nil &&
+		u.UserId == nil && u.StartDate == nil && u.EndDate == nil && u.AllowOverlap == nil
+}
+
+// affectsOverlap reports whether the update changes a field that decides
+// whether the subscription overlaps others. Other updates are not checked, so
+// that subscriptions overlapping since before the check existed can still be
+// edited.
+func (u SubscriptionUpdate) affectsOverlap() bool {
+	return u.ServiceName != nil || u.UserId != nil || u.StartDate != nil || u.EndDate != nil || u.AllowOverlap != nil
+}
+
+// overlapFilter returns the filter of the active subscriptions sub shares
+// months with, sub itself included.
+func overlapFilter(sub subscription.Subscription) SubscriptionFilter {
+	filter := SubscriptionFilter{UserId: &sub.UserId, ServiceName: sub.ServiceName, ActiveFrom: sub.StartDate}
+	if sub.EndDate != nil {
+		filter.ActiveTo = *sub.EndDate
+	}
+	return filter
+}
+
+func overlapError(other subscription.Subscription) error {
+	return fmt.Errorf("%w: subscription %s of the user to %s is active in the same months", ErrOverlap, other.ID, other.ServiceName)
+}
+
+// applyUpdate sets the fields of update on sub.
+func applyUpdate(sub *subscription.Subscription, update SubscriptionUpdate) {
+	if update.ServiceName != nil {
+		sub.ServiceName = *update.ServiceName
+	}
+	if update.Price != nil {
+		sub.Price = *update.Price
+	}
+	if update.Currency != nil {
+		sub.Currency = *update.Currency
+	}
+	if update.BillingPeriod != nil {
+		sub.BillingPeriod = *update.BillingPeriod
+	}
+	if update.UserId != nil {
+		sub.UserId = *update.UserId
+	}
+	if update.StartDate != nil {
+		sub.StartDate = *update.StartDate
+	}
+	if update.EndDate != nil {
+		sub.EndDate = nil
+		if !update.EndDate.IsZero() {
+			endDate := *update.EndDate
+			sub.EndDate = &endDate
+		}
+	}
+	if update.AllowOverlap != nil {
+		sub.AllowOverlap = *update.AllowOverlap
+	}
+}
+
+// currentPrice returns the current price of sub once changes are recorded in
+// its price history. It is stored on the subscription itself, which reads
+// still replace with the current price of the history: a price set from a
+// future month does not show in the stored one before the next update.
+func currentPrice(sub subscription.Subscription, changes []subscription.PriceChange) int {
+	sub.Prices = append([]subscription.PriceChange(nil), sub.Prices...)
+	for _, change := range changes {
+		sub.Prices = upsertPriceChange(sub.Prices, change, time.Time{})
+	}
+	return sub.CurrentPrice()
+}
+
+// priceChanges returns the price history entries to store for the price of
+// update, none when that price is already in effect. Subscriptions created
+// before the history existed first get their previous price recorded from the
+// start date.
+func priceChanges(current subscription.Subscription, update SubscriptionUpdate) []subscription.PriceChange {
+	if update.Price == nil {
+		return nil
+	}
+
+	startDate := current.StartDate
+	if update.StartDate != nil {
+		startDate = *update.StartDate
+	}
+
+	effectiveFrom := update.PriceEffectiveFrom
+	if effectiveFrom.IsZero() {
+		effectiveFrom = subscription.CurrentMonth()
+	}
+	if effectiveFrom.Before(startDate) {
+		effectiveFrom = startDate
+	}
+	if current.PriceAt(effectiveFrom) == *update.Price {
+		return nil
+	}
+
+	changes := []subscription.PriceChange{}
+	if len(current.Prices) == 0 && effectiveFrom.After(current.StartDate) {
+		changes = append(changes, subscription.PriceChange{
+			SubscriptionID: current.ID,
+			EffectiveFrom:  current.StartDate,
+			Price:          current.Price,
+		})
+	}
+	return append(changes, subscription.PriceChange{
+		SubscriptionID: current.ID,
+		EffectiveFrom:  effectiveFrom,
+		Price:          *update.Price,
+	})
+}
@@ -0,0 +1,457 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"emtest/api-service/audit"
+	"emtest/api-service/currency"
+	"emtest/api-service/subscription"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceChanges(t *testing.T) {
+	jan := subscription.NewMonth(2025, time.January)
+	apr := subscription.NewMonth(2025, time.April)
+	price := func(p int) *int { return &p }
+
+	legacy := subscription.Subscription{ID: uuid.New(), Price: 400, StartDate: jan}
+
+	changes := priceChanges(legacy, SubscriptionUpdate{Price: price(500), PriceEffectiveFrom: apr})
+	assert.Equal(t, []subscription.PriceChange{
+		{SubscriptionID: legacy.ID, EffectiveFrom: jan, Price: 400},
+		{SubscriptionID: legacy.ID, EffectiveFrom: apr, Price: 500},
+	}, changes)
+
+	assert.Empty(t, priceChanges(legacy, SubscriptionUpdate{Price: price(400), PriceEffectiveFrom: apr}))
+	assert.Empty(t, priceChanges(legacy, SubscriptionUpdate{ServiceName: new(string)}))
+
+	tracked := legacy
+	tracked.Prices = []subscription.PriceChange{{SubscriptionID: legacy.ID, EffectiveFrom: jan, Price: 400}}
+
+	changes = priceChanges(tracked, SubscriptionUpdate{Price: price(300), PriceEffectiveFrom: subscription.NewMonth(2024, time.June)})
+	assert.Equal(t, []subscription.PriceChange{{SubscriptionID: legacy.ID, EffectiveFrom: jan, Price: 300}}, changes)
+}
+
+// testStore checks the behaviour every Store implementation has to share.
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	ctx := context.Background()
+	jan := subscription.NewMonth(2024, time.January)
+	feb := subscription.NewMonth(2024, time.February)
+	mar := subscription.NewMonth(2024, time.March)
+	jun := subscription.NewMonth(2024, time.June)
+
+	t.Run("CreateGet", func(t *testing.T) {
+		store := newStore(t)
+
+		sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: uuid.New(), StartDate: jan, EndDate: &mar}
+		assert.NoError(t, store.Create(ctx, &sub))
+		assert.NotEqual(t, uuid.Nil, sub.ID)
+
+		got, err := store.Get(ctx, sub.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "Test Yandex", got.ServiceName)
+		assert.Equal(t, "RUB", got.Currency)
+		assert.Equal(t, subscription.Monthly, got.BillingPeriod)
+		assert.Equal(t, mar, *got.EndDate)
+		assert.Len(t, got.Prices, 1)
+
+		_, err = store.Get(ctx, uuid.New(), true)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		store := newStore(t)
+
+		userId := uuid.New()
+		for _, sub := range []subscription.Subscription{
+			{ServiceName: "Test A", Price: 300, UserId: userId, StartDate: jan, EndDate: &jan},
+			{ServiceName: "Test B", Price: 100, UserId: userId, StartDate: jan},
+			{ServiceName: "Test C", Price: 200, UserId: userId, StartDate: jun},
+			{ServiceName: "Test D", Price: 200, UserId: userId, StartDate: mar},
+			{ServiceName: "Test E", Price: 500, UserId: uuid.New(), StartDate: jan},
+		} {
+			assert.NoError(t, store.Create(ctx, &sub))
+		}
+
+		subs, err := store.List(ctx, SubscriptionFilter{UserId: &userId, ActiveTo: feb}, ListOptions{Limit: 10, Sort: "price", Order: "asc"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Test B", "Test A"}, serviceNames(subs))
+
+		var listed []subscription.Subscription
+		opts := ListOptions{Limit: 2, Sort: "price", Order: "desc"}
+		for pages := 0; pages < 3; pages++ {
+			page, err := store.List(ctx, SubscriptionFilter{UserId: &userId, ActiveFrom: mar}, opts)
+			assert.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			listed = append(listed, page...)
+			last := page[len(page)-1]
+			opts.After = &ListCursor{Value: last.Price, ID: last.ID}
+		}
+
+		assert.Len(t, listed, 3)
+		assert.ElementsMatch(t, []string{"Test B", "Test C", "Test D"}, serviceNames(listed))
+		assert.Equal(t, []int{200, 200, 100}, []int{listed[0].Price, listed[1].Price, listed[2].Price})
+
+		_, err = store.List(ctx, SubscriptionFilter{}, ListOptions{Limit: 1, Sort: "user_id; DROP TABLE subscriptions", Order: "asc"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store := newStore(t)
+
+		sub := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: uuid.New(), StartDate: jan, EndDate: &jun}
+		assert.NoError(t, store.Create(ctx, &sub))
+
+		price := 700
+		updated, err := store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price, PriceEffectiveFrom: mar, EndDate: &subscription.Month{}})
+		assert.NoError(t, err)
+		assert.Equal(t, 700, updated.Price)
+		assert.Nil(t, updated.EndDate)
+		assert.Equal(t, 500, updated.PriceAt(feb))
+		assert.Equal(t, 700, updated.PriceAt(mar))
+
+		price = 800
+		updated, err = store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price, PriceEffectiveFrom: mar})
+		assert.NoError(t, err)
+		assert.Len(t, updated.Prices, 2)
+		assert.Equal(t, 800, updated.PriceAt(mar))
+
+		unchanged, err := store.Update(ctx, sub.ID, SubscriptionUpdate{})
+		assert.NoError(t, err)
+		assert.Equal(t, updated.Price, unchanged.Price)
+
+		_, err = store.Update(ctx, uuid.New(), SubscriptionUpdate{Price: &price})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("UpdateFuturePrice", func(t *testing.T) {
+		store := newStore(t)
+		now := subscription.CurrentMonth()
+		next := now.AddMonths(2)
+
+		sub := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: uuid.New(), StartDate: now.AddMonths(-3)}
+		assert.NoError(t, store.Create(ctx, &sub))
+
+		price := 700
+		updated, err := store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price, PriceEffectiveFrom: next})
+		assert.NoError(t, err)
+		assert.Equal(t, 500, updated.Price, "the price in effect today is kept")
+		assert.Equal(t, 700, updated.PriceAt(next))
+
+		got, err := store.Get(ctx, sub.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 500, got.Price)
+
+		price = 600
+		updated, err = store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price, PriceEffectiveFrom: now.AddMonths(-1)})
+		assert.NoError(t, err)
+		assert.Equal(t, 600, updated.Price)
+		assert.Equal(t, 700, updated.PriceAt(next))
+	})
+
+	t.Run("UpdateDates", func(t *testing.T) {
+		store := newStore(t)
+
+		sub := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: uuid.New(), StartDate: feb, EndDate: &jun}
+		assert.NoError(t, store.Create(ctx, &sub))
+
+		var validationErrors subscription.ValidationErrors
+		_, err := store.Update(ctx, sub.ID, SubscriptionUpdate{EndDate: &jan})
+		assert.ErrorAs(t, err, &validationErrors)
+		_, err = store.Update(ctx, sub.ID, SubscriptionUpdate{StartDate: &subscription.Month{}})
+		assert.ErrorAs(t, err, &validationErrors)
+
+		got, err := store.Get(ctx, sub.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, jun, *got.EndDate)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		store := newStore(t)
+		userId := uuid.New()
+
+		first := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: userId, StartDate: jan, EndDate: &mar}
+		assert.NoError(t, store.Create(ctx, &first))
+
+		second := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: userId, StartDate: mar}
+		assert.ErrorIs(t, store.Create(ctx, &second), ErrOverlap)
+
+		second.StartDate = jun
+		assert.NoError(t, store.Create(ctx, &second))
+
+		_, err := store.Update(ctx, second.ID, SubscriptionUpdate{StartDate: &feb})
+		assert.ErrorIs(t, err, ErrOverlap)
+
+		price := 600
+		_, err = store.Update(ctx, first.ID, SubscriptionUpdate{Price: &price})
+		assert.NoError(t, err)
+
+		allow := true
+		_, err = store.Update(ctx, second.ID, SubscriptionUpdate{StartDate: &feb, AllowOverlap: &allow})
+		assert.NoError(t, err)
+
+		other := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: uuid.New(), StartDate: jan}
+		assert.NoError(t, store.Create(ctx, &other))
+
+		assert.NoError(t, store.Delete(ctx, first.ID))
+		third := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: userId, StartDate: jan, EndDate: &jan}
+		assert.NoError(t, store.Create(ctx, &third))
+
+		_, err = store.Restore(ctx, first.ID)
+		assert.ErrorIs(t, err, ErrOverlap)
+	})
+
+	t.Run("ConcurrentOverlap", func(t *testing.T) {
+		store := newStore(t)
+		userId := uuid.New()
+
+		const writers = 8
+		errs := make(chan error, writers)
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sub := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: userId, StartDate: jan}
+				errs <- store.Create(ctx, &sub)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			if err == nil {
+				created++
+			}
+		}
+		assert.Equal(t, 1, created)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		store := newStore(t)
+
+		userId := uuid.New()
+		sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: userId, StartDate: jan}
+		assert.NoError(t, store.Create(ctx, &sub))
+
+		assert.NoError(t, store.Delete(ctx, sub.ID))
+		assert.ErrorIs(t, store.Delete(ctx, sub.ID), ErrNotFound)
+
+		_, err := store.Get(ctx, sub.ID, false)
+		assert.ErrorIs(t, err, ErrNotFound)
+		deleted, err := store.Get(ctx, sub.ID, true)
+		assert.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+
+		price := 500
+		_, err = store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		opts := ListOptions{Limit: 10, Sort: "created_at", Order: "asc"}
+		subs, err := store.List(ctx, SubscriptionFilter{UserId: &userId}, opts)
+		assert.NoError(t, err)
+		assert.Empty(t, subs)
+		subs, err = store.List(ctx, SubscriptionFilter{UserId: &userId, IncludeDeleted: true}, opts)
+		assert.NoError(t, err)
+		assert.Len(t, subs, 1)
+
+		count := 0
+		err = store.AggregateCost(ctx, SubscriptionFilter{UserId: &userId}, func(subscription.Subscription) error {
+			count++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+
+		restored, err := store.Restore(ctx, sub.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		_, err = store.Restore(ctx, sub.ID)
+		assert.ErrorIs(t, err, ErrNotDeleted)
+		_, err = store.Restore(ctx, uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		assert.NoError(t, store.Delete(ctx, sub.ID))
+
+		purged, err := store.Purge(ctx, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = store.Purge(ctx, time.Now().Add(time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		_, err = store.Get(ctx, sub.ID, true)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		store := newStore(t)
+		actor := "auditor-" + uuid.NewString()
+		ctx := audit.WithActor(ctx, actor)
+
+		sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: uuid.New(), StartDate: jan}
+		assert.NoError(t, store.Create(ctx, &sub))
+
+		price := 500
+		_, err := store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price})
+		assert.NoError(t, err)
+		_, err = store.Update(ctx, sub.ID, SubscriptionUpdate{Price: &price})
+		assert.NoError(t, err)
+		assert.NoError(t, store.Delete(ctx, sub.ID))
+		_, err = store.Restore(ctx, sub.ID)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Delete(ctx, sub.ID))
+		_, err = store.Purge(context.Background(), time.Now().Add(time.Second))
+		assert.NoError(t, err)
+
+		entries, err := store.ListAudit(ctx, AuditFilter{SubscriptionID: &sub.ID}, 10, nil)
+		assert.NoError(t, err)
+
+		actions := make([]audit.Action, len(entries))
+		for i, entry := range entries {
+			actions[i] = entry.Action
+		}
+		assert.Equal(t, []audit.Action{audit.Create, audit.Update, audit.Delete, audit.Restore, audit.Delete, audit.Purge}, actions)
+		if len(entries) != 6 {
+			return
+		}
+
+		assert.Equal(t, actor, entries[0].Actor)
+		assert.Equal(t, audit.SystemActor, entries[5].Actor)
+		assert.Contains(t, entries[0].Changes, audit.Change{Field: "service_name", New: "Test Yandex"})
+		assert.Len(t, entries[1].Changes, 1)
+		assert.Equal(t, "price", entries[1].Changes[0].Field)
+		assert.EqualValues(t, 400, entries[1].Changes[0].Old)
+		assert.EqualValues(t, 500, entries[1].Changes[0].New)
+		assert.Equal(t, "deleted_at", entries[2].Changes[0].Field)
+		assert.Nil(t, entries[3].Changes[0].New)
+
+		page, err := store.ListAudit(ctx, AuditFilter{Actor: actor}, 2, nil)
+		assert.NoError(t, err)
+		assert.Len(t, page, 2)
+		page, err = store.ListAudit(ctx, AuditFilter{Actor: actor}, 10, &ListCursor{Value: page[1].CreatedAt, ID: page[1].ID})
+		assert.NoError(t, err)
+		assert.Len(t, page, 3)
+
+		deletions, err := store.ListAudit(ctx, AuditFilter{SubscriptionID: &sub.ID, Action: audit.Delete}, 10, nil)
+		assert.NoError(t, err)
+		assert.Len(t, deletions, 2)
+
+		future, err := store.ListAudit(ctx, AuditFilter{SubscriptionID: &sub.ID, Since: time.Now().Add(time.Hour)}, 10, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, future)
+		past, err := store.ListAudit(ctx, AuditFilter{SubscriptionID: &sub.ID, Until: time.Now().Add(-time.Hour)}, 10, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, past)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		store := newStore(t)
+		userId := uuid.New()
+		failed := errors.New("failed")
+
+		kept := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: userId, StartDate: jan}
+		err := store.Transaction(ctx, func(tx SubscriptionRepository) error {
+			return tx.Create(ctx, &kept)
+		})
+		assert.NoError(t, err)
+
+		discarded := subscription.Subscription{ServiceName: "Test Google", Price: 100, UserId: userId, StartDate: jan}
+		err = store.Transaction(ctx, func(tx SubscriptionRepository) error {
+			if err := tx.Create(ctx, &discarded); err != nil {
+				return err
+			}
+			if err := tx.Delete(ctx, kept.ID); err != nil {
+				return err
+			}
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		_, err = store.Get(ctx, kept.ID, false)
+		assert.NoError(t, err)
+		_, err = store.Get(ctx, discarded.ID, true)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		entries, err := store.ListAudit(ctx, AuditFilter{SubscriptionID: &kept.ID}, 10, nil)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("AggregateCost", func(t *testing.T) {
+		store := newStore(t)
+
+		userId := uuid.New()
+		for _, sub := range []subscription.Subscription{
+			{ServiceName: "Test A", Price: 100, UserId: userId, StartDate: jan, EndDate: &feb},
+			{ServiceName: "Test B", Price: 200, UserId: userId, StartDate: jun},
+			{ServiceName: "Test C", Price: 400, UserId: uuid.New(), StartDate: jan},
+		} {
+			assert.NoError(t, store.Create(ctx, &sub))
+		}
+
+		total := 0.0
+		err := store.AggregateCost(ctx, SubscriptionFilter{UserId: &userId, ActiveFrom: jan, ActiveTo: mar}, func(sub subscription.Subscription) error {
+			assert.NotEmpty(t, sub.Prices)
+			total += sub.Cost(jan, mar, subscription.Accrual)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 200.0, total)
+
+		stop := errors.New("stop")
+		err = store.AggregateCost(ctx, SubscriptionFilter{}, func(subscription.Subscription) error { return stop })
+		assert.ErrorIs(t, err, stop)
+	})
+
+	t.Run("CountByService", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, sub := range []subscription.Subscription{
+			{ServiceName: "Test A", Price: 100, UserId: uuid.New(), StartDate: jan},
+			{ServiceName: "Test A", Price: 100, UserId: uuid.New(), StartDate: feb},
+			{ServiceName: "Test B", Price: 200, UserId: uuid.New(), StartDate: jan, EndDate: &jan},
+			{ServiceName: "Test C", Price: 400, UserId: uuid.New(), StartDate: jun},
+		} {
+			assert.NoError(t, store.Create(ctx, &sub))
+		}
+
+		counts, err := store.CountByService(ctx, SubscriptionFilter{ActiveFrom: mar, ActiveTo: mar})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"Test A": 2}, counts)
+	})
+
+	t.Run("Rates", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, rate := range []currency.Rate{{Currency: "USD", Rate: 90}, {Currency: "EUR", Rate: 100}, {Currency: "USD", Rate: 95}} {
+			assert.NoError(t, store.SetRate(ctx, &rate))
+		}
+
+		rates, err := store.ListRates(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, rates, 2)
+		assert.Equal(t, "EUR", rates[0].Currency)
+		assert.Equal(t, 95.0, rates[1].Rate)
+
+		assert.NoError(t, store.DeleteRate(ctx, "USD"))
+		assert.ErrorIs(t, store.DeleteRate(ctx, "USD"), ErrNotFound)
+	})
+}
+
+func serviceNames(subs []subscription.Subscription) []string {
+	names := make([]string, len(subs))
+	for i, sub := range subs {
+		names[i] = sub.ServiceName
+	}
+	return names
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"emtest/api-service/audit"
+	"emtest/api-service/config"
+	"emtest/api-service/subscription"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		db := openSQLite(t)
+		t.Cleanup(func() {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		})
+		return NewGormRepository(db)
+	})
+}
+
+func TestSQLiteAuditLogAppendOnly(t *testing.T) {
+	db := openSQLite(t)
+	repo := NewGormRepository(db)
+
+	sub := subscription.Subscription{ServiceName: "Netflix", Price: 400, UserId: uuid.New(), StartDate: subscription.CurrentMonth()}
+	assert.NoError(t, repo.Create(context.Background(), &sub))
+
+	assert.ErrorContains(t, db.Model(&audit.Entry{}).Where("1 = 1").Update("actor", "someone else").Error, "append-only")
+	assert.ErrorContains(t, db.Where("1 = 1").Delete(&audit.Entry{}).Error, "append-only")
+}
+
+func TestSQLiteHealthChecker(t *testing.T) {
+	db := openSQLite(t)
+	var checker HealthChecker = NewGormRepository(db)
+	ctx := context.Background()
+
+	assert.NoError(t, checker.Ping(ctx))
+	assert.NoError(t, checker.CheckSchema(ctx))
+
+	assert.NoError(t, db.Exec("DELETE FROM schema_migrations WHERE version = (SELECT MAX(version) FROM schema_migrations)").Error)
+	assert.ErrorIs(t, checker.CheckSchema(ctx), ErrSchemaMismatch)
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	assert.Error(t, checker.Ping(ctx))
+}
+
+func TestConnect(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	database := config.Database{Driver: DriverSQLite, Path: filepath.Join(dir, "test.db"), ConnectTimeout: 100 * time.Millisecond}
+
+	// The directory of the database file does not exist, so SQLite cannot open it.
+	_, err := Connect(context.Background(), database)
+	assert.ErrorContains(t, err, "database is not available after 100ms")
+
+	// The database becomes available while Connect waits for it.
+	time.AfterFunc(100*time.Millisecond, func() { os.Mkdir(dir, 0o755) })
+	database.ConnectTimeout = 5 * time.Second
+	db, err := Connect(context.Background(), database)
+	if assert.NoError(t, err) {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}
+
+	_, err = Connect(context.Background(), config.Database{Driver: DriverMemory})
+	assert.ErrorIs(t, err, errNoSQLSchema)
+}
+
+// openSQLite opens a migrated SQLite database in a temporary directory.
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := Open(config.Database{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Could not open database: %s", err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Could not load migrations: %s", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Could not migrate database: %s", err)
+	}
+
+	return db
+}
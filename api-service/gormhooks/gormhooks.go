@@ -0,0 +1,44 @@
+// Package gormhooks registers callbacks around every operation GORM runs, for
+// the plugins observing queries.
+package gormhooks
+
+import "gorm.io/gorm"
+
+// Hooks returns the callbacks run before and after operation: create, query,
+// update, delete, row or raw.
+type Hooks func(operation string) (before, after func(*gorm.DB))
+
+// gormRegister is the callback position returned by the Before and After
+// methods of the GORM callback processors.
+type gormRegister interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// Register installs the hooks of every operation, named
+// <prefix>:before_<operation> and <prefix>:after_<operation>.
+func Register(db *gorm.DB, prefix string, hooks Hooks) error {
+	callbacks := db.Callback()
+
+	processors := []struct {
+		operation     string
+		before, after gormRegister
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+
+	for _, p := range processors {
+		before, after := hooks(p.operation)
+		if err := p.before.Register(prefix+":before_"+p.operation, before); err != nil {
+			return err
+		}
+		if err := p.after.Register(prefix+":after_"+p.operation, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
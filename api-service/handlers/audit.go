@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"emtest/api-service/audit"
+	"emtest/api-service/db"
+	"emtest/api-service/problem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// @Summary Get subscription history
+// @Description Журнал изменений подписки: кто, когда и какие поля изменил, со старыми и новыми значениями.
+// @Description Доступен и для удаленных подписок, в том числе окончательно удаленных. Для подписок, созданных до появления журнала, история пуста
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
+// @Param limit query int false "Page size" minimum(1) maximum(500) default(50)
+// @Param cursor query string false "Cursor from pagination.next_cursor of the previous page"
+// @Success 200 {object} AuditPage "Audit entries, oldest first"
+// @Failure 400 {object} problem.Problem "Malformed ID or pagination parameters"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/{id}/history [get]
+func (h *Handler) GetSubscriptionHistory(c *fiber.Ctx) error {
+
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
+
+	page, err := parsePageRequest(c.Query("limit"), c.Query("cursor"), "", "")
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid pagination parameters", err.Error()).Send(c)
+	}
+
+	entries, err := h.audit.ListAudit(c.UserContext(), db.AuditFilter{SubscriptionID: &id}, page.limit+1, page.after)
+	if err != nil {
+		return subscriptionError(c, id, err)
+	}
+	// Subscriptions created before the audit log existed have no entries,
+	// while purged ones only have entries left.
+	if len(entries) == 0 && page.after == nil {
+		if _, err := h.subscriptions.Get(c.UserContext(), id, true); err != nil {
+			return subscriptionError(c, id, err)
+		}
+		entries = []audit.Entry{}
+	}
+
+	return c.JSON(page.auditPage(entries))
+}
+
+// @Summary Get audit log
+// @Description Журнал изменений всех подписок с фильтрами
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param subscription_id query string false "Filter by subscription ID (UUID format)" Format(uuid)
+// @Param actor query string false "Filter by actor"
+// @Param action query string false "Filter by action" Enums(create, update, delete, restore, purge)
+// @Param since query string false "Only entries created at or after this time (RFC 3339)" Format(date-time)
+// @Param until query string false "Only entries created before this time (RFC 3339)" Format(date-time)
+// @Param limit query int false "Page size" minimum(1) maximum(500) default(50)
+// @Param cursor query string false "Cursor from pagination.next_cursor of the previous page"
+// @Success 200 {object} AuditPage "Audit entries, oldest first"
+// @Failure 400 {object} problem.Problem "Invalid filters or pagination parameters"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin token"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/admin/audit [get]
+func (h *Handler) GetAuditLog(c *fiber.Ctx) error {
+
+	page, err := parsePageRequest(c.Query("limit"), c.Query("cursor"), "", "")
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid pagination parameters", err.Error()).Send(c)
+	}
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid filters", err.Error()).Send(c)
+	}
+
+	entries, err := h.audit.ListAudit(c.UserContext(), filter, page.limit+1, page.after)
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to fetch audit log", err.Error()).Send(c)
+	}
+
+	return c.JSON(page.auditPage(entries))
+}
+
+// auditFilter validates the subscription_id, actor, action, since and until
+// query filters of the audit log.
+func auditFilter(c *fiber.Ctx) (db.AuditFilter, error) {
+	filter := db.AuditFilter{Actor: c.Query("actor"), Action: audit.Action(c.Query("action"))}
+
+	if id := c.Query("subscription_id"); id != "" {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return filter, fmt.Errorf("subscription_id: invalid UUID")
+		}
+		filter.SubscriptionID = &parsed
+	}
+
+	if filter.Action != "" && !audit.Actions[filter.Action] {
+		return filter, fmt.Errorf("action must be one of create, update, delete, restore, purge")
+	}
+
+	bounds := []struct {
+		field string
+		time  *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	}
+	for _, bound := range bounds {
+		if value := c.Query(bound.field); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s: expected an RFC 3339 time, got %q", bound.field, value)
+			}
+			*bound.time = parsed
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"emtest/api-service/db"
+	"emtest/api-service/problem"
+	"emtest/api-service/subscription"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "best_effort"
+)
+
+const (
+	bulkCreate = "create"
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+// @description One operation of a bulk request. Create takes subscription,
+// @description update takes id and changes, delete takes id.
+type BulkOperation struct {
+	Op           string                     `json:"op" enums:"create,update,delete" example:"create"`
+	ID           *uuid.UUID                 `json:"id,omitempty"`
+	Subscription *subscription.Subscription `json:"subscription,omitempty"`
+	Changes      *UpdateSubscriptionRequest `json:"changes,omitempty"`
+}
+
+// @description Bulk request object
+type BulkRequest struct {
+	Mode       string          `json:"mode" enums:"atomic,best_effort" default:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// @description Outcome of one bulk operation. Status is the HTTP status the
+// @description operation would have had on its own endpoint, 424 for
+// @description operations rolled back because another one failed.
+type BulkResult struct {
+	Index        int                        `json:"index"`
+	Op           string                     `json:"op"`
+	ID           *uuid.UUID                 `json:"id,omitempty"`
+	Status       int                        `json:"status" example:"200"`
+	Error        *problem.Problem           `json:"error,omitempty"`
+	Subscription *subscription.Subscription `json:"subscription,omitempty"`
+}
+
+// @description Bulk response object
+type BulkResponse struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
+
+// errBulkFailed aborts the transaction of an atomic bulk request.
+var errBulkFailed = errors.New("bulk operation failed")
+
+// bulkItem is a validated bulk operation ready to be applied.
+type bulkItem struct {
+	op     string
+	id     uuid.UUID
+	sub    subscription.Subscription
+	update db.SubscriptionUpdate
+}
+
+// @Summary Bulk create, update and delete subscriptions
+// @Description Выполнение нескольких операций над подписками за один запрос. Каждая операция проверяется так же, как на своем эндпоинте.
+// @Description В режиме atomic операции выполняются в одной транзакции: при ошибке любой из них не применяется ни одна (ответ 422).
+// @Description В режиме best_effort применяются все корректные операции, результат каждой указан в results
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param body body BulkRequest true "Operations to apply"
+// @Success 200 {object} BulkResponse "Results of the operations"
+// @Failure 400 {object} problem.Problem "Invalid body, mode or number of operations"
+// @Failure 422 {object} BulkResponse "Atomic request not applied, see results"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/bulk [post]
+func (h *Handler) BulkSubscriptions(c *fiber.Ctx) error {
+
+	var req BulkRequest
+
+	if err := parseBody(c, &req); err != nil {
+		return problem.Body(err).Send(c)
+	}
+
+	if req.Mode == "" {
+		req.Mode = bulkAtomic
+	}
+	if req.Mode != bulkAtomic && req.Mode != bulkBestEffort {
+		return problem.New(http.StatusBadRequest, "Invalid mode", fmt.Sprintf("mode must be %s or %s", bulkAtomic, bulkBestEffort)).Send(c)
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > h.maxBulkOperations {
+		return problem.New(http.StatusBadRequest, "Invalid number of operations", fmt.Sprintf("A bulk request takes 1 to %d operations, got %d", h.maxBulkOperations, len(req.Operations))).Send(c)
+	}
+
+	response := BulkResponse{Mode: req.Mode, Results: make([]BulkResult, len(req.Operations))}
+	items := make([]*bulkItem, len(req.Operations))
+	valid := true
+	for i, operation := range req.Operations {
+		response.Results[i] = BulkResult{Index: i, Op: operation.Op, ID: operation.ID}
+		items[i] = prepareBulkItem(operation, &response.Results[i])
+		valid = valid && items[i] != nil
+	}
+
+	ctx := c.UserContext()
+	if req.Mode == bulkBestEffort {
+		for i, item := range items {
+			if item != nil {
+				applyBulkItem(ctx, h.subscriptions, item, &response.Results[i])
+			}
+		}
+		return c.JSON(response.count())
+	}
+
+	err := errBulkFailed
+	if valid {
+		err = h.subscriptions.Transaction(ctx, func(tx db.SubscriptionRepository) error {
+			for i, item := range items {
+				if !applyBulkItem(ctx, tx, item, &response.Results[i]) {
+					return errBulkFailed
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		for i := range response.Results {
+			result := &response.Results[i]
+			if result.Error == nil {
+				*result = BulkResult{
+					Index:  i,
+					Op:     result.Op,
+					ID:     req.Operations[i].ID,
+					Status: http.StatusFailedDependency,
+					Error:  problem.New(http.StatusFailedDependency, "Not applied", "Another operation of the atomic request failed"),
+				}
+			}
+		}
+		return c.Status(http.StatusUnprocessableEntity).JSON(response.count())
+	}
+
+	return c.JSON(response.count())
+}
+
+// prepareBulkItem validates operation the way its own endpoint does. It
+// returns nil and fills result in when the operation is invalid.
+func prepareBulkItem(operation BulkOperation, result *BulkResult) *bulkItem {
+	fail := func(p *problem.Problem) *bulkItem {
+		result.Status, result.Error = p.Status, p
+		return nil
+	}
+
+	if operation.Op != bulkCreate && operation.Op != bulkUpdate && operation.Op != bulkDelete {
+		return fail(problem.New(http.StatusBadRequest, "Bad request", fmt.Sprintf("op must be one of %s, %s, %s", bulkCreate, bulkUpdate, bulkDelete)))
+	}
+
+	item := &bulkItem{op: operation.Op}
+	if operation.Op != bulkCreate {
+		if operation.ID == nil {
+			return fail(problem.New(http.StatusBadRequest, "Bad request", "Parameter 'id' is required"))
+		}
+		item.id = *operation.ID
+	}
+
+	switch operation.Op {
+	case bulkCreate:
+		if operation.Subscription == nil {
+			return fail(problem.New(http.StatusBadRequest, "Bad request", "Parameter 'subscription' is required"))
+		}
+		item.sub = *operation.Subscription
+		if err := item.sub.Prepare(); err != nil {
+			return fail(problem.Validation(err))
+		}
+	case bulkUpdate:
+		if operation.Changes == nil {
+			return fail(problem.New(http.StatusBadRequest, "Bad request", "Parameter 'changes' is required"))
+		}
+		update, err := operation.Changes.subscriptionUpdate()
+		if err != nil {
+			return fail(problem.Validation(err))
+		}
+		item.update = update
+	}
+
+	return item
+}
+
+// applyBulkItem applies item with subscriptions, fills result in and reports
+// whether it succeeded.
+func applyBulkItem(ctx context.Context, subscriptions db.SubscriptionRepository, item *bulkItem, result *BulkResult) bool {
+	var err error
+
+	switch item.op {
+	case bulkCreate:
+		sub := item.sub
+		if err = subscriptions.Create(ctx, &sub); err == nil {
+			result.ID, result.Subscription = &sub.ID, &sub
+		}
+	case bulkUpdate:
+		var updated subscription.Subscription
+		if updated, err = subscriptions.Update(ctx, item.id, item.update); err == nil {
+			result.Subscription = &updated
+		}
+	case bulkDelete:
+		err = subscriptions.Delete(ctx, item.id)
+	}
+
+	result.Status = http.StatusOK
+	if err != nil {
+		result.Error = subscriptionProblem(item.id, err)
+		result.Status = result.Error.Status
+	}
+	return err == nil
+}
+
+// count fills in the number of succeeded and failed operations.
+func (r BulkResponse) count() BulkResponse {
+	r.Succeeded, r.Failed = 0, 0
+	for _, result := range r.Results {
+		if result.Error == nil {
+			r.Succeeded++
+		} else {
+			r.Failed++
+		}
+	}
+	return r
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// subscriptionID reads the subscription id from the {id} path parameter or,
+// on deprecated v1 routes, from the id query parameter.
+func subscriptionID(c *fiber.Ctx) (uuid.UUID, error) {
+	id := c.Params("id")
+	if id == "" {
+		id = c.Query("id")
+	}
+	if id == "" {
+		return uuid.Nil, fmt.Errorf("Parameter 'id' is required")
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("Parameter 'id' must be a UUID, got %q", id)
+	}
+	return parsed, nil
+}
+
+// RegisterV1Compat mounts the routes addressing a subscription with the id
+// query parameter. They are deprecated aliases of the /subscriptions/{id}
+// routes and must be registered before them, so that /subscriptions/prices
+// is not taken for an id.
+func (h *Handler) RegisterV1Compat(v1 fiber.Router) {
+	v1.Get("/subscriptions", deprecatedQueryID("", fiber.MethodGet), h.GetSubscriptions)
+	v1.Put("/subscriptions", deprecatedQueryID("", fiber.MethodPatch), h.updateSubscriptionByQuery)
+	v1.Delete("/subscriptions", deprecatedQueryID("", fiber.MethodDelete), h.deleteSubscriptionByQuery)
+	v1.Get("/subscriptions/prices", deprecatedQueryID("/prices", fiber.MethodGet), h.getPriceHistoryByQuery)
+}
+
+// deprecatedQueryID marks requests using the id query parameter as deprecated
+// and points clients at the path-parameter route via the Link header. When
+// the successor route takes another method, as PATCH succeeds the partial
+// PUT update, the method is named in the link: PUT /subscriptions/{id}
+// replaces the whole subscription instead.
+func deprecatedQueryID(suffix, successorMethod string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id := c.Query("id"); id != "" {
+			c.Set("Deprecation", "true")
+			if parsed, err := uuid.Parse(id); err == nil {
+				base := strings.TrimSuffix(c.Path(), suffix)
+				link := fmt.Sprintf(`<%s/%s%s>; rel="successor-version"`, base, parsed, suffix)
+				if successorMethod != c.Method() {
+					link += fmt.Sprintf(`; method="%s"`, successorMethod)
+				}
+				c.Set(fiber.HeaderLink, link)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// @Summary Update subscription by query id
+// @Description Устаревший вариант PATCH /subscriptions/{id}: частичное обновление подписки, id передается в query.
+// @Description Ответ содержит заголовки Deprecation и Link на новый маршрут
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id query string true "Subscription ID (UUID format)" Format(uuid)
+// @Param subscription body UpdateSubscriptionRequest true "Subscription object with updated fields"
+// @Success 200 {object} subscription.Subscription "Updated subscription"
+// @Failure 400 {object} problem.Problem "Bad request - missing ID, invalid body or end_date before start_date"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 409 {object} problem.Problem "Overlapping subscription"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Deprecated
+// @Router /api/v1/subscriptions [put]
+func (h *Handler) updateSubscriptionByQuery(c *fiber.Ctx) error {
+	return h.UpdateSubscription(c)
+}
+
+// @Summary Delete subscription by query id
+// @Description Устаревший вариант DELETE /subscriptions/{id}, id передается в query.
+// @Description Ответ содержит заголовки Deprecation и Link на новый маршрут
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id query string true "Subscription ID (UUID format)" Format(uuid)
+// @Success 200 {object} SuccessResponse "Success message"
+// @Failure 400 {object} problem.Problem "Bad request - missing ID"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Deprecated
+// @Router /api/v1/subscriptions [delete]
+func (h *Handler) deleteSubscriptionByQuery(c *fiber.Ctx) error {
+	return h.DeleteSubscription(c)
+}
+
+// @Summary Get subscription price history by query id
+// @Description Устаревший вариант GET /subscriptions/{id}/prices, id передается в query.
+// @Description Ответ содержит заголовки Deprecation и Link на новый маршрут
+// @Tags subscriptions
+// @Produce json
+// @Param id query string true "Subscription ID (UUID format)" Format(uuid)
+// @Param include_deleted query bool false "Admin only: return the history even if the subscription is deleted"
+// @Success 200 {array} subscription.PriceChange "Price changes ordered by effective month"
+// @Failure 400 {object} problem.Problem "Bad request - missing ID"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Deprecated
+// @Router /api/v1/subscriptions/prices [get]
+func (h *Handler) getPriceHistoryByQuery(c *fiber.Ctx) error {
+	return h.GetPriceHistory(c)
+}
@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"context"
+	"emtest/api-service/currency"
+	"emtest/api-service/db"
+	"emtest/api-service/problem"
+	"emtest/api-service/subscription"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// @description Success calculate object
+type SuccessCostResponse struct {
+	Total    float64 `json:"total"`
+	Currency string  `json:"currency" example:"RUB"`
+}
+
+const (
+	groupByService = "service_name"
+	groupByUser    = "user_id"
+	groupByBoth    = "both"
+)
+
+// @description Cost of one group of subscriptions
+type CostGroup struct {
+	ServiceName string     `json:"service_name,omitempty"`
+	UserId      *uuid.UUID `json:"user_id,omitempty"`
+	Total       float64    `json:"total"`
+}
+
+// @description Cost breakdown object
+type CostBreakdownResponse struct {
+	GroupBy  string      `json:"group_by"`
+	Total    float64     `json:"total"`
+	Currency string      `json:"currency" example:"RUB"`
+	Groups   []CostGroup `json:"groups"`
+}
+
+// maxSeriesMonths caps the length of a monthly time series.
+const maxSeriesMonths = 240
+
+// maxPeriodMonths caps the length of the period costs are calculated over, as
+// every subscription is billed month by month within it.
+const maxPeriodMonths = 1200
+
+// @description Amount due in one calendar month
+type MonthlyCost struct {
+	Month subscription.Month `json:"month" swaggertype:"string" format:"MM-YYYY" example:"01-2025"`
+	Total float64            `json:"total"`
+}
+
+// @description Monthly cost time series object
+type MonthlyCostResponse struct {
+	Total    float64       `json:"total"`
+	Currency string        `json:"currency" example:"RUB"`
+	Months   []MonthlyCost `json:"months"`
+}
+
+// costRequest holds the parameters shared by the cost endpoints.
+type costRequest struct {
+	from     subscription.Month
+	to       subscription.Month
+	mode     subscription.AccountingMode
+	currency string
+	filter   db.SubscriptionFilter
+
+	subscriptions db.SubscriptionRepository
+	rates         db.RateRepository
+	loadedRates   currency.Rates
+	factors       map[string]float64
+}
+
+// @Summary Calculate total cost of subscriptions
+// @Description Подсчет стоимости подписок за период: цена умножается на число месяцев, в которые подписка активна внутри периода.
+// @Description Если end_date не указан, период заканчивается текущим месяцем, а если start_date в будущем — месяцем start_date. Если start_date не указан, период начинается с начала подписки.
+// @Description Суммы пересчитываются в target_currency по курсам из /api/v1/admin/rates.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Period start (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Period end (MM-YYYY format)" Format(MM-YYYY)
+// @Param mode query string false "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged" Enums(accrual, cash) default(accrual)
+// @Param target_currency query string false "Currency of the result (ISO 4217)" default(RUB)
+// @Success 200 {object} SuccessCostResponse "Total cost calculation result"
+// @Failure 400 {object} problem.Problem "Invalid period, accounting mode or currency"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 422 {object} problem.Problem "Missing exchange rate"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/calculate [get]
+func (h *Handler) CalculateTotalCost(c *fiber.Ctx) error {
+
+	req, costErr := h.newCostRequest(c)
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	totalCost := 0.0
+	costErr = req.aggregate(c, func(sub subscription.Subscription, factor float64) {
+		totalCost += sub.Cost(req.from, req.to, req.mode) * factor
+	})
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	return c.JSON(SuccessCostResponse{Total: subscription.RoundAmount(totalCost), Currency: req.currency})
+}
+
+// @Summary Cost breakdown of subscriptions
+// @Description Подсчет стоимости подписок по тем же фильтрам, что и /calculate, с группировкой по сервису, пользователю или по обоим
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param group_by query string false "Grouping key" Enums(service_name, user_id, both) default(service_name)
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Period start (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Period end (MM-YYYY format)" Format(MM-YYYY)
+// @Param mode query string false "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged" Enums(accrual, cash) default(accrual)
+// @Param target_currency query string false "Currency of the result (ISO 4217)" default(RUB)
+// @Success 200 {object} CostBreakdownResponse "Grouped totals with the grand total"
+// @Failure 400 {object} problem.Problem "Invalid period, grouping, accounting mode or currency"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 422 {object} problem.Problem "Missing exchange rate"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/calculate/breakdown [get]
+func (h *Handler) CalculateCostBreakdown(c *fiber.Ctx) error {
+
+	response, costErr := h.costBreakdown(c)
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	return c.JSON(response)
+}
+
+// costBreakdown groups the cost of the requested subscriptions by the group_by
+// query key, largest groups first.
+func (h *Handler) costBreakdown(c *fiber.Ctx) (CostBreakdownResponse, *problem.Problem) {
+	groupBy := c.Query("group_by", groupByService)
+	if groupBy != groupByService && groupBy != groupByUser && groupBy != groupByBoth {
+		return CostBreakdownResponse{}, problem.New(http.StatusBadRequest, "Invalid grouping", fmt.Sprintf("group_by must be one of %s, %s, %s", groupByService, groupByUser, groupByBoth))
+	}
+
+	req, costErr := h.newCostRequest(c)
+	if costErr != nil {
+		return CostBreakdownResponse{}, costErr
+	}
+
+	type groupKey struct {
+		serviceName string
+		userId      uuid.UUID
+	}
+
+	response := CostBreakdownResponse{GroupBy: groupBy, Currency: req.currency, Groups: []CostGroup{}}
+	groups := make(map[groupKey]int)
+
+	costErr = req.aggregate(c, func(sub subscription.Subscription, factor float64) {
+		cost := sub.Cost(req.from, req.to, req.mode) * factor
+
+		var key groupKey
+		if groupBy != groupByUser {
+			key.serviceName = sub.ServiceName
+		}
+		if groupBy != groupByService {
+			key.userId = sub.UserId
+		}
+
+		index, exists := groups[key]
+		if !exists {
+			group := CostGroup{ServiceName: key.serviceName}
+			if groupBy != groupByService {
+				group.UserId = &sub.UserId
+			}
+			index = len(response.Groups)
+			groups[key] = index
+			response.Groups = append(response.Groups, group)
+		}
+
+		response.Groups[index].Total += cost
+		response.Total += cost
+	})
+	if costErr != nil {
+		return CostBreakdownResponse{}, costErr
+	}
+
+	sort.Slice(response.Groups, func(i, j int) bool {
+		left, right := response.Groups[i], response.Groups[j]
+		if left.Total != right.Total {
+			return left.Total > right.Total
+		}
+		if left.ServiceName != right.ServiceName {
+			return left.ServiceName < right.ServiceName
+		}
+		return left.UserId != nil && right.UserId != nil && left.UserId.String() < right.UserId.String()
+	})
+
+	response.Total = subscription.RoundAmount(response.Total)
+	for i := range response.Groups {
+		response.Groups[i].Total = subscription.RoundAmount(response.Groups[i].Total)
+	}
+
+	return response, nil
+}
+
+// @Summary Monthly cost time series
+// @Description Сумма к оплате по каждому календарному месяцу периода по тем же фильтрам, что и /calculate
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string true "Period start (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Period end (MM-YYYY format), current month by default" Format(MM-YYYY)
+// @Param mode query string false "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged" Enums(accrual, cash) default(accrual)
+// @Param target_currency query string false "Currency of the result (ISO 4217)" default(RUB)
+// @Success 200 {object} MonthlyCostResponse "One row per month of the period"
+// @Failure 400 {object} problem.Problem "Invalid period, accounting mode or currency"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 422 {object} problem.Problem "Missing exchange rate"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/calculate/monthly [get]
+func (h *Handler) CalculateMonthlyCost(c *fiber.Ctx) error {
+
+	if c.Query("start_date") == "" {
+		return problem.New(http.StatusBadRequest, "Invalid period", "start_date is required").Send(c)
+	}
+
+	req, costErr := h.newCostRequest(c)
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	months := subscription.MonthsBetween(req.from, req.to)
+	if months > maxSeriesMonths {
+		return problem.New(http.StatusBadRequest, "Invalid period", fmt.Sprintf("period must not be longer than %d months", maxSeriesMonths)).Send(c)
+	}
+
+	response := MonthlyCostResponse{Currency: req.currency, Months: make([]MonthlyCost, months)}
+	for i := range response.Months {
+		response.Months[i].Month = req.from.AddMonths(i)
+	}
+
+	costErr = req.aggregate(c, func(sub subscription.Subscription, factor float64) {
+		for i := range response.Months {
+			amount := sub.AmountIn(response.Months[i].Month, req.mode) * factor
+			response.Months[i].Total += amount
+			response.Total += amount
+		}
+	})
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	response.Total = subscription.RoundAmount(response.Total)
+	for i := range response.Months {
+		response.Months[i].Total = subscription.RoundAmount(response.Months[i].Total)
+	}
+
+	return c.JSON(response)
+}
+
+// newCostRequest parses the period, filters, accounting mode and target
+// currency of a cost endpoint.
+func (h *Handler) newCostRequest(c *fiber.Ctx) (*costRequest, *problem.Problem) {
+	from, to, err := parsePeriod(c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		return nil, problem.New(http.StatusBadRequest, "Invalid period", err.Error())
+	}
+
+	filter, err := h.subscriptionFilter(c)
+	if errors.Is(err, errAdminOnly) {
+		return nil, problem.New(http.StatusForbidden, "Forbidden", err.Error())
+	}
+	if err != nil {
+		return nil, problem.New(http.StatusBadRequest, "Invalid filters", err.Error())
+	}
+	filter.ActiveFrom, filter.ActiveTo = from, to
+
+	mode, err := subscription.ParseAccountingMode(c.Query("mode"))
+	if err != nil {
+		return nil, problem.New(http.StatusBadRequest, "Invalid accounting mode", err.Error())
+	}
+
+	target := strings.ToUpper(c.Query("target_currency", currency.Base))
+	if err := validate.Var(target, "iso4217"); err != nil {
+		return nil, problem.New(http.StatusBadRequest, "Invalid currency", fmt.Sprintf("target_currency %q is not an ISO 4217 code", target))
+	}
+
+	return &costRequest{
+		from:          from,
+		to:            to,
+		mode:          mode,
+		currency:      target,
+		filter:        filter,
+		subscriptions: h.subscriptions,
+		rates:         h.rates,
+		factors:       make(map[string]float64),
+	}, nil
+}
+
+// tracerName names the tracer of the cost calculation spans.
+const tracerName = "emtest/api-service/handlers"
+
+// aggregate calls fn for every subscription active in the requested period
+// along with the factor converting its amounts into the target currency.
+func (r *costRequest) aggregate(c *fiber.Ctx, fn func(sub subscription.Subscription, factor float64)) *problem.Problem {
+	// The span is a child of the request span and the parent of the queries.
+	ctx, span := otel.Tracer(tracerName).Start(c.UserContext(), "cost.aggregate", trace.WithAttributes(
+		attribute.String("cost.from", r.from.String()),
+		attribute.String("cost.to", r.to.String()),
+		attribute.String("cost.mode", string(r.mode)),
+		attribute.String("cost.currency", r.currency),
+	))
+	defer span.End()
+
+	count := 0
+	err := r.subscriptions.AggregateCost(ctx, r.filter, func(sub subscription.Subscription) error {
+		factor, err := r.factor(ctx, sub.Currency)
+		if err != nil {
+			return err
+		}
+		fn(sub, factor)
+		count++
+		return nil
+	})
+	span.SetAttributes(attribute.Int("cost.subscriptions", count))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if errors.Is(err, currency.ErrMissingRate) {
+		return problem.New(http.StatusUnprocessableEntity, "Missing exchange rate", err.Error())
+	}
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to count", err.Error())
+	}
+
+	return nil
+}
+
+// factor returns the multiplier converting amounts in code into the target
+// currency. Exchange rates are only loaded when some subscription is priced
+// in another currency.
+func (r *costRequest) factor(ctx context.Context, code string) (float64, error) {
+	if factor, exists := r.factors[code]; exists {
+		return factor, nil
+	}
+
+	if code == r.currency || (code == "" && r.currency == currency.Base) {
+		r.factors[code] = 1
+		return 1, nil
+	}
+
+	if r.loadedRates == nil {
+		stored, err := r.rates.ListRates(ctx)
+		if err != nil {
+			return 0, err
+		}
+		r.loadedRates = currency.NewRates(stored)
+	}
+
+	factor, err := r.loadedRates.Factor(code, r.currency)
+	if err != nil {
+		return 0, err
+	}
+	r.factors[code] = factor
+	return factor, nil
+}
+
+// parsePeriod turns the start_date/end_date query values into a month window.
+// A missing start leaves the window open at the beginning, a missing end closes it
+// at the current month, or at the start month when it lies in the future. The
+// window may not be longer than maxPeriodMonths, counted from the current month
+// when the start is open.
+func parsePeriod(startDate, endDate string) (subscription.Month, subscription.Month, error) {
+	var from subscription.Month
+	to := subscription.CurrentMonth()
+
+	if startDate != "" {
+		month, err := subscription.ParseMonth(startDate)
+		if err != nil {
+			return from, to, fmt.Errorf("start_date: %w", err)
+		}
+		from = month
+	}
+
+	if endDate != "" {
+		month, err := subscription.ParseMonth(endDate)
+		if err != nil {
+			return from, to, fmt.Errorf("end_date: %w", err)
+		}
+		to = month
+	} else if to.Before(from) {
+		to = from
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("end_date must not be before start_date")
+	}
+
+	start := from
+	if start.IsZero() {
+		start = subscription.CurrentMonth()
+	}
+	if subscription.MonthsBetween(start, to) > maxPeriodMonths {
+		return from, to, fmt.Errorf("period must not be longer than %d months", maxPeriodMonths)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"bufio"
+	"emtest/api-service/problem"
+	"emtest/api-service/subscription"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// exportBatchSize is the number of subscriptions an export reads at a time.
+const exportBatchSize = maxPageLimit
+
+// exportFormat is the file format of an export.
+type exportFormat string
+
+const (
+	exportCSV   exportFormat = "csv"
+	exportJSONL exportFormat = "jsonl"
+)
+
+func parseExportFormat(format string) (exportFormat, error) {
+	switch exportFormat(strings.ToLower(format)) {
+	case "", exportCSV:
+		return exportCSV, nil
+	case exportJSONL:
+		return exportJSONL, nil
+	}
+	return "", fmt.Errorf("format must be %s or %s", exportCSV, exportJSONL)
+}
+
+func (f exportFormat) contentType() string {
+	if f == exportJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// setHeaders marks the response as a download of name in the format.
+func (f exportFormat) setHeaders(c *fiber.Ctx, name string) {
+	c.Set(fiber.HeaderContentType, f.contentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, f))
+}
+
+// recordWriter writes export records one by one, as CSV rows or JSON lines.
+type recordWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+// newRecordWriter returns a writer of the format, starting CSV files with the
+// header row.
+func newRecordWriter(w io.Writer, format exportFormat, header []string) (*recordWriter, error) {
+	if format == exportJSONL {
+		return &recordWriter{json: json.NewEncoder(w)}, nil
+	}
+
+	writer := &recordWriter{csv: csv.NewWriter(w)}
+	return writer, writer.csv.Write(header)
+}
+
+// write writes value as a JSON line, or row as a CSV row.
+func (w *recordWriter) write(value interface{}, row []string) error {
+	if w.json != nil {
+		return w.json.Encode(value)
+	}
+	return w.csv.Write(row)
+}
+
+func (w *recordWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+// subscriptionColumns are the CSV columns of a subscription export, named
+// after the JSON fields of subscription.Subscription.
+var subscriptionColumns = []string{
+	"id", "service_name", "price", "currency", "billing_period", "user_id",
+	"start_date", "end_date", "allow_overlap", "created_at", "updated_at", "deleted_at",
+}
+
+func subscriptionRow(sub subscription.Subscription) []string {
+	row := []string{
+		sub.ID.String(),
+		sub.ServiceName,
+		strconv.Itoa(sub.Price),
+		sub.Currency,
+		string(sub.BillingPeriod),
+		sub.UserId.String(),
+		sub.StartDate.String(),
+		"",
+		strconv.FormatBool(sub.AllowOverlap),
+		sub.CreatedAt.UTC().Format(time.RFC3339),
+		sub.UpdatedAt.UTC().Format(time.RFC3339),
+		"",
+	}
+	if sub.EndDate != nil {
+		row[7] = sub.EndDate.String()
+	}
+	if sub.DeletedAt != nil {
+		row[11] = sub.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return row
+}
+
+// @Summary Export subscriptions
+// @Description Выгрузка всех подписок, подходящих под фильтры, в CSV или JSON Lines.
+// @Description Ответ передается потоком, подписки читаются из базы порциями
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format" Enums(csv, jsonl) default(csv)
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Only subscriptions active on or after this month (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Only subscriptions active on or before this month (MM-YYYY format)" Format(MM-YYYY)
+// @Param sort query string false "Sort field" Enums(created_at, price, start_date) default(created_at)
+// @Param order query string false "Sort order" Enums(asc, desc) default(asc)
+// @Success 200 {file} file "Subscriptions, one per row or line"
+// @Failure 400 {object} problem.Problem "Invalid format, filters or sort parameters"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/export [get]
+func (h *Handler) ExportSubscriptions(c *fiber.Ctx) error {
+
+	format, err := parseExportFormat(c.Query("format"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid format", err.Error()).Send(c)
+	}
+
+	page, err := parsePageRequest("", "", c.Query("sort"), c.Query("order"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid sort parameters", err.Error()).Send(c)
+	}
+	page.limit = exportBatchSize
+
+	filter, err := h.listFilter(c)
+	if err != nil {
+		return filterError(c, err)
+	}
+	// The body is written after the handler returns, when the request
+	// buffers the filter points into may have been reused.
+	filter.ServiceName = utils.CopyString(filter.ServiceName)
+
+	// The first batch is read up front so that a failing database still gets
+	// a proper error response.
+	ctx := c.UserContext()
+	subs, err := h.subscriptions.List(ctx, filter, page.options())
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to fetch subscriptions", err.Error()).Send(c)
+	}
+
+	format.setHeaders(c, "subscriptions")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer, err := newRecordWriter(w, format, subscriptionColumns)
+		for err == nil {
+			batch := subs
+			if len(batch) > page.limit {
+				batch = batch[:page.limit]
+			}
+			for _, sub := range batch {
+				if err = writer.write(sub, subscriptionRow(sub)); err != nil {
+					break
+				}
+			}
+			if err = writer.flush(); err != nil {
+				break
+			}
+			if err = w.Flush(); err != nil || len(subs) <= page.limit {
+				break
+			}
+
+			page.after = page.cursorAfter(batch[len(batch)-1])
+			subs, err = h.subscriptions.List(ctx, filter, page.options())
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Subscription export aborted")
+		}
+	})
+
+	return nil
+}
+
+// breakdownColumns are the CSV columns of a cost breakdown export.
+var breakdownColumns = []string{"service_name", "user_id", "total", "currency"}
+
+// breakdownRecord is a JSON line of a cost breakdown export.
+type breakdownRecord struct {
+	CostGroup
+	Currency string `json:"currency"`
+}
+
+// @Summary Export cost breakdown
+// @Description Выгрузка разбивки стоимости подписок (как в /calculate/breakdown) в CSV или JSON Lines, по строке на группу
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format" Enums(csv, jsonl) default(csv)
+// @Param group_by query string false "Grouping key" Enums(service_name, user_id, both) default(service_name)
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Period start (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Period end (MM-YYYY format)" Format(MM-YYYY)
+// @Param mode query string false "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged" Enums(accrual, cash) default(accrual)
+// @Param target_currency query string false "Currency of the result (ISO 4217)" default(RUB)
+// @Success 200 {file} file "One group per row or line, largest first"
+// @Failure 400 {object} problem.Problem "Invalid format, period, grouping, accounting mode or currency"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 422 {object} problem.Problem "Missing exchange rate"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/calculate/breakdown/export [get]
+func (h *Handler) ExportCostBreakdown(c *fiber.Ctx) error {
+
+	format, err := parseExportFormat(c.Query("format"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid format", err.Error()).Send(c)
+	}
+
+	breakdown, costErr := h.costBreakdown(c)
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	format.setHeaders(c, "cost-breakdown")
+	writer, err := newRecordWriter(c, format, breakdownColumns)
+	for _, group := range breakdown.Groups {
+		if err != nil {
+			break
+		}
+		userId := ""
+		if group.UserId != nil {
+			userId = group.UserId.String()
+		}
+		row := []string{group.ServiceName, userId, strconv.FormatFloat(group.Total, 'f', 2, 64), breakdown.Currency}
+		err = writer.write(breakdownRecord{CostGroup: group, Currency: breakdown.Currency}, row)
+	}
+	if err == nil {
+		err = writer.flush()
+	}
+	return err
+}
@@ -1,313 +1,513 @@
 package handlers
 
 import (
+	"emtest/api-service/config"
+	"emtest/api-service/problem"
 	"emtest/api-service/subscription"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"emtest/api-service/db"
+	"emtest/api-service/middleware"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
-var validate *validator.Validate
+var validate = subscription.NewValidator()
 
-func init() {
-	validate = validator.New()
+// defaultMaxBulkOperations is used when bulk.max_operations is not set.
+const defaultMaxBulkOperations = 100
+
+// Handler serves the subscription API on top of a storage backend.
+// adminToken unlocks admin-only query parameters such as include_deleted.
+type Handler struct {
+	subscriptions     db.SubscriptionRepository
+	audit             db.AuditRepository
+	rates             db.RateRepository
+	adminToken        string
+	maxBulkOperations int
 }
 
-// @Description Error response object
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+func New(subscriptions db.SubscriptionRepository, audit db.AuditRepository, rates db.RateRepository, cfg config.Config) *Handler {
+	h := &Handler{
+		subscriptions:     subscriptions,
+		audit:             audit,
+		rates:             rates,
+		adminToken:        cfg.Admin.Token,
+		maxBulkOperations: cfg.Bulk.MaxOperations,
+	}
+	if h.maxBulkOperations <= 0 {
+		h.maxBulkOperations = defaultMaxBulkOperations
+	}
+	return h
 }
 
+// errAdminOnly is returned when a non-admin asks for deleted subscriptions.
+var errAdminOnly = errors.New("include_deleted requires a valid " + middleware.AdminTokenHeader + " header")
+
 // @Description Success response object
 type SuccessResponse struct {
 	Message string `json:"message" example:"Subscription deleted successfully"`
 }
 
-// @description Success calculate object
-type SuccessCostResponse struct {
-	Total int `json:"total"`
+// UpdateSubscriptionRequest holds the fields to change. PriceEffectiveFrom is the
+// first month billed at the new price and defaults to the current month; it is
+// only accepted along with the price.
+type UpdateSubscriptionRequest struct {
+	ServiceName        *string                     `json:"service_name,omitempty"`
+	Price              *int                        `json:"price,omitempty"`
+	Currency           *string                     `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"`
+	BillingPeriod      *subscription.BillingPeriod `json:"billing_period,omitempty" validate:"omitempty,oneof=weekly monthly quarterly yearly" enums:"weekly,monthly,quarterly,yearly"`
+	UserId             *uuid.UUID                  `json:"user_id,omitempty"`
+	StartDate          *subscription.Month         `json:"start_date,omitempty" swaggertype:"string" format:"MM-YYYY" example:"01-2025"`
+	EndDate            *subscription.Month         `json:"end_date,omitempty" swaggertype:"string" format:"MM-YYYY" example:"12-2025"`
+	AllowOverlap       *bool                       `json:"allow_overlap,omitempty"`
+	PriceEffectiveFrom *subscription.Month         `json:"price_effective_from,omitempty" validate:"excluded_without=Price" swaggertype:"string" format:"MM-YYYY" example:"03-2025"`
 }
 
-type UpdateSubscriptionRequest struct {
-	ServiceName *string    `json:"service_name,omitempty"`
-	Price       *int       `json:"price,omitempty"`
-	UserId      *uuid.UUID `json:"user_id,omitempty"`
-	StartDate   *string    `json:"start_date,omitempty"`
-	EndDate     *string    `json:"end_date,omitempty"`
+// parseBody parses the request body into out. A JSON value rejected for its
+// format, such as a malformed month or UUID, is reported as a
+// *subscription.FormatError naming its field.
+func parseBody(c *fiber.Ctx, out interface{}) error {
+	err := c.BodyParser(out)
+	if err != nil && c.Is("json") {
+		return subscription.FieldFormatError(c.Body(), out, err)
+	}
+	return err
 }
 
 // @Summary Create a new subscription
-// @Description Создание новой подписки
+// @Description Создание новой подписки. end_date не может быть раньше start_date.
+// @Description Подписка не может пересекаться по месяцам с другой активной подпиской пользователя на тот же сервис, если ни у одной из них не указан allow_overlap
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param body body subscription.Subscription true "Body of the request"
 // @Success 200 {object} subscription.Subscription
-// @Failure 400 {object} ErrorResponse "Bad Request"
-// @Failure 500 {object} ErrorResponse "Internal Server Error"
+// @Failure 400 {object} problem.Problem "Bad Request"
+// @Failure 409 {object} problem.Problem "Overlapping subscription"
+// @Failure 500 {object} problem.Problem "Internal Server Error"
 // @Router /api/v1/subscriptions [post]
-func CreateSubscription(c *fiber.Ctx) error {
+func (h *Handler) CreateSubscription(c *fiber.Ctx) error {
 
 	var sub subscription.Subscription
 
-	if err := c.BodyParser(&sub); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+	if err := parseBody(c, &sub); err != nil {
+		return problem.Body(err).Send(c)
 	}
 
-	if err := validate.Struct(sub); err != nil {
-		var errors []string
-		for _, err := range err.(validator.ValidationErrors) {
-			errors = append(errors, fmt.Sprintf("Field '%s' failed validation: %s", err.Field(), err.Tag()))
-		}
-
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Validation failed",
-			Message: strings.Join(errors, "; "),
-		})
+	if err := sub.Prepare(); err != nil {
+		return problem.Validation(err).Send(c)
 	}
 
-	if err := subscription.ValidateDateFormat(sub.StartDate); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Invalid date format",
-			Message: err.Error(),
-		})
+	err := h.subscriptions.Create(c.UserContext(), &sub)
+	if errors.Is(err, db.ErrOverlap) {
+		return subscriptionError(c, sub.ID, err)
 	}
-
-	result := db.DB.Create(&sub)
-	if result.Error != nil {
-		return c.Status(http.StatusInternalServerError).JSON(ErrorResponse{
-			Error:   "Failed to create subscription",
-			Message: result.Error.Error(),
-		})
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to create subscription", err.Error()).Send(c)
 	}
 
 	return c.Status(http.StatusOK).JSON(sub)
 }
 
 // @Summary Get subscriptions
-// @Description Получение подписки по её id. Если id не указано, возвращаются все
+// @Description Страница подписок с фильтрами, сортировкой и курсорной пагинацией.
+// @Description Параметр id устарел: используйте GET /api/v1/subscriptions/{id}
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param id query string false "Subscription ID (UUID format)" Format(uuid)
-// @Success 200 {array} subscription.Subscription "List of subscriptions"
-// @Success 200 {object} subscription.Subscription "Single subscription when ID provided"
-// @Failure 404 {object} ErrorResponse "Subscription not found"
-// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Param id query string false "Deprecated: Subscription ID (UUID format)" Format(uuid)
+// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Only subscriptions active on or after this month (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Only subscriptions active on or before this month (MM-YYYY format)" Format(MM-YYYY)
+// @Param sort query string false "Sort field" Enums(created_at, price, start_date) default(created_at)
+// @Param order query string false "Sort order" Enums(asc, desc) default(asc)
+// @Param limit query int false "Page size" minimum(1) maximum(500) default(50)
+// @Param cursor query string false "Cursor from pagination.next_cursor of the previous page"
+// @Success 200 {object} SubscriptionsPage "Page of subscriptions, or the subscription itself with the deprecated id"
+// @Failure 400 {object} problem.Problem "Invalid filters, pagination parameters or ID"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
 // @Router /api/v1/subscriptions [get]
-func GetSubscriptions(c *fiber.Ctx) error {
+func (h *Handler) GetSubscriptions(c *fiber.Ctx) error {
 
 	id := c.Query("id")
 
 	if id == "" {
-		var subs []subscription.Subscription
-
-		result := db.DB.Find(&subs)
-		if result.Error != nil {
-			return c.Status(http.StatusInternalServerError).JSON(ErrorResponse{
-				Error:   "Failed to fetch subscriptions",
-				Message: result.Error.Error(),
-			})
-		}
+		return h.listSubscriptions(c)
+	}
 
-		return c.JSON(subs)
+	return h.GetSubscription(c)
+}
+
+// @Summary Get subscription
+// @Description Получение подписки по её id
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
+// @Param include_deleted query bool false "Admin only: return the subscription even if it is deleted"
+// @Success 200 {object} subscription.Subscription "Subscription"
+// @Failure 400 {object} problem.Problem "Malformed ID"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Router /api/v1/subscriptions/{id} [get]
+func (h *Handler) GetSubscription(c *fiber.Ctx) error {
+
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
+
+	includeDeleted, err := h.includeDeleted(c)
+	if err != nil {
+		return filterError(c, err)
 	}
-	var sub subscription.Subscription
 
-	result := db.DB.First(&sub, "id = ?", id)
-	if result.RowsAffected == 0 {
-		return c.Status(http.StatusNotFound).JSON(ErrorResponse{
-			Error: fmt.Sprintf("Sunscription %s not found", id),
-		})
+	sub, err := h.subscriptions.Get(c.UserContext(), id, includeDeleted)
+	if err != nil {
+		return subscriptionError(c, id, err)
 	}
 
 	return c.JSON(sub)
 }
 
+func (h *Handler) listSubscriptions(c *fiber.Ctx) error {
+
+	page, err := parsePageRequest(c.Query("limit"), c.Query("cursor"), c.Query("sort"), c.Query("order"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid pagination parameters", err.Error()).Send(c)
+	}
+
+	filter, err := h.listFilter(c)
+	if err != nil {
+		return filterError(c, err)
+	}
+
+	subs, err := h.subscriptions.List(c.UserContext(), filter, page.options())
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to fetch subscriptions", err.Error()).Send(c)
+	}
+
+	return c.JSON(page.page(subs))
+}
+
+// listFilter validates the user_id, service_name, include_deleted, start_date
+// and end_date query filters of the subscription list.
+func (h *Handler) listFilter(c *fiber.Ctx) (db.SubscriptionFilter, error) {
+	filter, err := h.subscriptionFilter(c)
+	if err != nil {
+		return filter, err
+	}
+
+	bounds := []struct {
+		field string
+		month *subscription.Month
+	}{
+		{"start_date", &filter.ActiveFrom},
+		{"end_date", &filter.ActiveTo},
+	}
+	for _, bound := range bounds {
+		if value := c.Query(bound.field); value != "" {
+			month, err := subscription.ParseMonth(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %w", bound.field, err)
+			}
+			*bound.month = month
+		}
+	}
+
+	return filter, nil
+}
+
+// subscriptionFilter validates the user_id, service_name and include_deleted
+// query filters.
+func (h *Handler) subscriptionFilter(c *fiber.Ctx) (db.SubscriptionFilter, error) {
+	filter := db.SubscriptionFilter{ServiceName: c.Query("service_name")}
+
+	if userId := c.Query("user_id"); userId != "" {
+		parsed, err := uuid.Parse(userId)
+		if err != nil {
+			return filter, fmt.Errorf("user_id: invalid UUID")
+		}
+		filter.UserId = &parsed
+	}
+
+	includeDeleted, err := h.includeDeleted(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.IncludeDeleted = includeDeleted
+
+	return filter, nil
+}
+
+// includeDeleted reads the include_deleted query flag, which only admins may set.
+func (h *Handler) includeDeleted(c *fiber.Ctx) (bool, error) {
+	value := c.Query("include_deleted")
+	if value == "" {
+		return false, nil
+	}
+
+	includeDeleted, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("include_deleted: expected a boolean, got %q", value)
+	}
+	if includeDeleted && !middleware.IsAdmin(c, h.adminToken) {
+		return false, errAdminOnly
+	}
+	return includeDeleted, nil
+}
+
+// filterError responds to invalid query filters, with 403 when a non-admin
+// asked for deleted subscriptions.
+func filterError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errAdminOnly) {
+		return problem.New(http.StatusForbidden, "Forbidden", err.Error()).Send(c)
+	}
+
+	return problem.New(http.StatusBadRequest, "Invalid filters", err.Error()).Send(c)
+}
+
 // @Summary Update subscription
-// @Description Обновление подписки по её id
+// @Description Частичное обновление подписки по её id: изменяются только переданные поля.
+// @Description Обновленная подписка проверяется целиком: end_date не может быть раньше start_date, в том числе сохраненного
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param id query string true "Subscription ID (UUID format)" Format(uuid)
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
 // @Param subscription body UpdateSubscriptionRequest true "Subscription object with updated fields"
 // @Success 200 {object} subscription.Subscription "Updated subscription"
-// @Failure 400 {object} ErrorResponse "Bad request - missing ID or invalid body"
-// @Failure 404 {object} ErrorResponse "Subscription not found"
-// @Router /api/v1/subscriptions [put]
-func UpdateSubscription(c *fiber.Ctx) error {
-	id := c.Query("id")
-	if id == "" {
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Bad request",
-			Message: "Parameter 'id' is required",
-		})
+// @Failure 400 {object} problem.Problem "Bad request - malformed ID, invalid body or end_date before start_date"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 409 {object} problem.Problem "Overlapping subscription"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/{id} [patch]
+func (h *Handler) UpdateSubscription(c *fiber.Ctx) error {
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
 	}
 
 	var updatedReq UpdateSubscriptionRequest
 
-	if err := c.BodyParser(&updatedReq); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+	if err := parseBody(c, &updatedReq); err != nil {
+		return problem.Body(err).Send(c)
 	}
-	updates := make(map[string]interface{})
 
-	if updatedReq.ServiceName != nil {
-		updates["service_name"] = *updatedReq.ServiceName
+	update, err := updatedReq.subscriptionUpdate()
+	if err != nil {
+		return problem.Validation(err).Send(c)
 	}
-	if updatedReq.Price != nil {
-		updates["price"] = *updatedReq.Price
+
+	return h.applyUpdate(c, id, update)
+}
+
+// subscriptionUpdate validates the request and returns the update it asks for.
+func (r UpdateSubscriptionRequest) subscriptionUpdate() (db.SubscriptionUpdate, error) {
+	// Empty values leave the field unchanged, as omitted ones do.
+	if r.Currency != nil && *r.Currency == "" {
+		r.Currency = nil
 	}
-	if updatedReq.UserId != nil {
-		updates["user_id"] = *updatedReq.UserId
+	if r.BillingPeriod != nil && *r.BillingPeriod == "" {
+		r.BillingPeriod = nil
 	}
-	if updatedReq.StartDate != nil {
-		if err := subscription.ValidateDateFormat(*updatedReq.StartDate); err != nil {
-			return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "Invalid date format",
-				Message: err.Error(),
-			})
-		}
-		updates["start_date"] = *updatedReq.StartDate
-	}
-	if updatedReq.EndDate != nil {
-		if err := subscription.ValidateDateFormat(*updatedReq.EndDate); err != nil {
-			return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "Invalid date format",
-				Message: err.Error(),
-			})
-		}
-		updates["end_date"] = *updatedReq.EndDate
+	if r.Currency != nil {
+		upper := strings.ToUpper(*r.Currency)
+		r.Currency = &upper
 	}
 
-	if len(updates) == 0 {
-		var currentSub subscription.Subscription
-		result := db.DB.First(&currentSub, "id = ?", id)
-		if result.RowsAffected == 0 {
-			return c.Status(http.StatusNotFound).JSON(ErrorResponse{
-				Error:   "Subscription not found",
-				Message: fmt.Sprintf("Subscription %s not found", id),
-			})
-		}
-		return c.Status(http.StatusOK).JSON(currentSub)
+	if err := validate.Struct(r); err != nil {
+		return db.SubscriptionUpdate{}, err
 	}
 
-	result := db.DB.Model(&subscription.Subscription{}).Where("id = ?", id).Updates(updates)
-	if result.RowsAffected == 0 {
-		return c.Status(http.StatusNotFound).JSON(ErrorResponse{
-			Error:   "Subscription not found",
-			Message: fmt.Sprintf("Subscription %s not found", id),
-		})
+	update := db.SubscriptionUpdate{
+		ServiceName:   r.ServiceName,
+		Price:         r.Price,
+		Currency:      r.Currency,
+		BillingPeriod: r.BillingPeriod,
+		UserId:        r.UserId,
+		AllowOverlap:  r.AllowOverlap,
+	}
+	if r.StartDate != nil && !r.StartDate.IsZero() {
+		update.StartDate = r.StartDate
+	}
+	if r.EndDate != nil && !r.EndDate.IsZero() {
+		update.EndDate = r.EndDate
+	}
+	if r.PriceEffectiveFrom != nil {
+		update.PriceEffectiveFrom = *r.PriceEffectiveFrom
 	}
 
-	var updatedSubscription subscription.Subscription
-	db.DB.First(&updatedSubscription, "id = ?", id)
-	return c.Status(fiber.StatusOK).JSON(updatedSubscription)
+	return update, nil
 }
 
-// @Summary Delete subscription
-// @Description Удаление подписки по её id
+// @Summary Replace subscription
+// @Description Полная замена подписки по её id. Поля, которые не переданы, сбрасываются (end_date) или принимают значения по умолчанию
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param id query string true "Subscription ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
-// @Success 200 {object} SuccessResponse "Success message"
-// @Failure 400 {object} ErrorResponse "Bad request - missing ID"
-// @Failure 404 {object} ErrorResponse "Subscription not found"
-// @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /api/v1/subscriptions [delete]
-func DeleteSubscription(c *fiber.Ctx) error {
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
+// @Param subscription body subscription.Subscription true "New subscription state"
+// @Success 200 {object} subscription.Subscription "Replaced subscription"
+// @Failure 400 {object} problem.Problem "Bad request - malformed ID, invalid body or end_date before start_date"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 409 {object} problem.Problem "Overlapping subscription"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/{id} [put]
+func (h *Handler) ReplaceSubscription(c *fiber.Ctx) error {
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
 
-	id := c.Query("id")
-	if id == "" {
-		return c.Status(http.StatusBadRequest).JSON(ErrorResponse{
-			Error:   "Bad request",
-			Message: "Parameter 'id' is required",
-		})
+	var sub subscription.Subscription
+
+	if err := parseBody(c, &sub); err != nil {
+		return problem.Body(err).Send(c)
 	}
 
-	result := db.DB.Delete(&subscription.Subscription{}, "id = ?", id)
-	if result.RowsAffected == 0 {
-		return c.Status(http.StatusNotFound).JSON(ErrorResponse{
-			Error:   "Subscription not found",
-			Message: fmt.Sprintf("Subscription %s not found", id),
-		})
+	if err := sub.Prepare(); err != nil {
+		return problem.Validation(err).Send(c)
 	}
 
-	if result.Error != nil {
-		return c.Status(http.StatusInternalServerError).JSON(ErrorResponse{
-			Error:   "Internal server error",
-			Message: result.Error.Error(),
-		})
+	endDate := subscription.Month{}
+	if sub.EndDate != nil {
+		endDate = *sub.EndDate
 	}
 
-	return c.JSON(SuccessResponse{Message: "Subscription deleted successfully"})
+	return h.applyUpdate(c, id, db.SubscriptionUpdate{
+		ServiceName:   &sub.ServiceName,
+		Price:         &sub.Price,
+		Currency:      &sub.Currency,
+		BillingPeriod: &sub.BillingPeriod,
+		UserId:        &sub.UserId,
+		StartDate:     &sub.StartDate,
+		EndDate:       &endDate,
+		AllowOverlap:  &sub.AllowOverlap,
+	})
+}
+
+// applyUpdate writes update to the subscription and responds with the updated subscription.
+func (h *Handler) applyUpdate(c *fiber.Ctx, id uuid.UUID, update db.SubscriptionUpdate) error {
+	updated, err := h.subscriptions.Update(c.UserContext(), id, update)
+	if err != nil {
+		return subscriptionError(c, id, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated)
 }
 
-// @Summary Calculate total cost of subscriptions
-// @Description Подсчет стоимости подписки по фильтрам
+// @Summary Delete subscription
+// @Description Удаление подписки по её id. Подписка помечается удаленной и может быть восстановлена до истечения срока хранения
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param user_id query string false "Filter by user ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
-// @Param service_name query string false "Filter by service name"
-// @Param start_date query string false "Filter by start date (MM-YYYY format)" Format(MM-YYYY)
-// @Param end_date query string false "Filter by end date (MM-YYYY format)" Format(MM-YYYY)
-// @Success 200 {object} SuccessCostResponse "Total cost calculation result"
-// @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /api/v1/subscriptions/calculate [get]
-func CalculateTotalCost(c *fiber.Ctx) error {
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid) Example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} SuccessResponse "Success message"
+// @Failure 400 {object} problem.Problem "Bad request - malformed ID"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/{id} [delete]
+func (h *Handler) DeleteSubscription(c *fiber.Ctx) error {
+
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
+
+	if err := h.subscriptions.Delete(c.UserContext(), id); err != nil {
+		return subscriptionError(c, id, err)
+	}
+
+	return c.JSON(SuccessResponse{Message: "Subscription deleted successfully"})
+}
+
+// @Summary Restore subscription
+// @Description Восстановление удаленной подписки по её id
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
+// @Success 200 {object} subscription.Subscription "Restored subscription"
+// @Failure 400 {object} problem.Problem "Bad request - malformed ID"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Failure 409 {object} problem.Problem "Subscription is not deleted or overlaps another subscription"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/{id}/restore [post]
+func (h *Handler) RestoreSubscription(c *fiber.Ctx) error {
+
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
 
-	var totalCost int64
-	filters := map[string]interface{}{
-		"user_id":      c.Query("user_id"),
-		"service_name": c.Query("service_name"),
-		"start_date":   c.Query("start_date"),
-		"end_date":     c.Query("end_date"),
+	restored, err := h.subscriptions.Restore(c.UserContext(), id)
+	if errors.Is(err, db.ErrNotDeleted) {
+		return problem.New(http.StatusConflict, "Subscription is not deleted", fmt.Sprintf("Subscription %s is not deleted", id)).Send(c)
+	}
+	if err != nil {
+		return subscriptionError(c, id, err)
 	}
 
-	query := db.DB.Model(&subscription.Subscription{})
-	query = applyFilters(query, filters)
+	return c.JSON(restored)
+}
+
+// @Summary Get subscription price history
+// @Description История изменения цены подписки. Подсчет стоимости использует цену, действовавшую в каждом месяце
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID format)" Format(uuid)
+// @Param include_deleted query bool false "Admin only: return the history even if the subscription is deleted"
+// @Success 200 {array} subscription.PriceChange "Price changes ordered by effective month"
+// @Failure 400 {object} problem.Problem "Bad request - malformed ID"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 404 {object} problem.Problem "Subscription not found"
+// @Router /api/v1/subscriptions/{id}/prices [get]
+func (h *Handler) GetPriceHistory(c *fiber.Ctx) error {
+
+	id, err := subscriptionID(c)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", err.Error()).Send(c)
+	}
 
-	row := query.Select("COALESCE(SUM(price), 0)").Row()
-	if err := row.Scan(&totalCost); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(ErrorResponse{
-			Error:   "Failed to count",
-			Message: err.Error(),
-		})
+	includeDeleted, err := h.includeDeleted(c)
+	if err != nil {
+		return filterError(c, err)
 	}
 
-	return c.JSON(SuccessCostResponse{Total: int(totalCost)})
+	sub, err := h.subscriptions.Get(c.UserContext(), id, includeDeleted)
+	if err != nil {
+		return subscriptionError(c, id, err)
+	}
+
+	return c.JSON(sub.PriceHistory())
 }
 
-func applyFilters(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+// subscriptionError responds to a failed repository call on subscription id.
+func subscriptionError(c *fiber.Ctx, id uuid.UUID, err error) error {
+	return subscriptionProblem(id, err).Send(c)
+}
 
-	conditions := map[string]string{
-		"user_id":      "user_id = ?",
-		"service_name": "service_name = ?",
-		"start_date":   "start_date >= ?",
-		"end_date":     "start_date <= ?",
+// subscriptionProblem describes a failed repository call on subscription id:
+// 404 when the subscription does not exist, 400 when the changes leave it
+// invalid and 409 when they make it overlap another subscription.
+func subscriptionProblem(id uuid.UUID, err error) *problem.Problem {
+	if errors.Is(err, db.ErrNotFound) {
+		return problem.New(http.StatusNotFound, "Subscription not found", fmt.Sprintf("Subscription %s not found", id))
 	}
-	for field, value := range filters {
-		if value != "" {
-			if condition, exists := conditions[field]; exists {
-				query = query.Where(condition, value)
-			}
-		}
+	if errors.As(err, new(subscription.ValidationErrors)) {
+		return problem.Validation(err)
+	}
+	if errors.Is(err, db.ErrOverlap) {
+		return problem.New(http.StatusConflict, "Overlapping subscription", err.Error())
 	}
 
-	return query
+	return problem.New(http.StatusInternalServerError, "Internal server error", err.Error())
 }
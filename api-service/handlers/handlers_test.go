@@ -2,33 +2,52 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"emtest/api-service/audit"
+	"emtest/api-service/config"
+	"emtest/api-service/currency"
 	"emtest/api-service/db"
+	"emtest/api-service/importer"
+	"emtest/api-service/middleware"
+	"emtest/api-service/problem"
+	"emtest/api-service/requestid"
 	"emtest/api-service/subscription"
+	"emtest/api-service/tracing"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
-	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testAdminToken        = "test-admin-token"
+	testMaxBulkOperations = 5
+	testGatewaySecret     = "test-gateway-secret"
 )
 
 type HandlersTestSuite struct {
 	suite.Suite
 	app      *fiber.App
-	testDB   *gorm.DB
-	pool     *dockertest.Pool
-	resource *dockertest.Resource
+	store    db.Store
+	newStore func(t *testing.T) db.Store
 }
 
 type TestUpdateSubscription struct {
@@ -38,107 +57,37 @@ type TestUpdateSubscription struct {
 	StartDate   string
 }
 
-func (suite *HandlersTestSuite) SetupSuite() {
-
-	pool, err := dockertest.NewPool("")
-	if err != nil {
-		logrus.Fatalf("Couldn't connect to docker: %s", err)
-	}
-	logrus.Info("Pool initialized...")
-	suite.pool = pool
-
-	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
-		Repository: "postgres",
-		Tag:        "15",
-		Env: []string{
-			"POSTGRES_PASSWORD=secret",
-			"POSTGRES_USER=test",
-			"POSTGRES_DB=test_db",
-			"listen_address = '*'",
-		},
-	}, func(config *docker.HostConfig) {
-		config.AutoRemove = true
-		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
-	})
+func month(dateStr string) subscription.Month {
+	m, err := subscription.ParseMonth(dateStr)
 	if err != nil {
-		logrus.Fatalf("Couldn't start resource: %s", err)
+		panic(err)
 	}
-	logrus.Info("Resource initialized...")
-	suite.resource = resource
-
-	var testDB *gorm.DB
-	dns := fmt.Sprintf(
-		"host=localhost user=test password=secret dbname=test_db port=%s sslmode=disable TimeZone=UTC",
-		resource.GetPort("5432/tcp"),
-	)
-
-	if err := pool.Retry(func() error {
-		logrus.Info("Attempt to connect to db...")
-		var err error
-		testDB, err = gorm.Open(
-			postgres.Open(dns),
-			&gorm.Config{},
-		)
-
-		if err != nil {
-			logrus.Infof("Database connection failed: %s", err)
-			return err
-		}
-
-		sqlDB, err := testDB.DB()
-		if err != nil {
-			logrus.Infof("Failed to get sql.DB: %s", err)
-			return err
-		}
-
-		if err := sqlDB.Ping(); err != nil {
-			logrus.Infof("Database ping failed: %s", err)
-			return err
-		}
+	return m
+}
 
-		logrus.Info("DB connected")
-		return nil
-	}); err != nil {
-		logrus.Fatalf("Failed to connecto to docker: %s", err)
-	}
-	suite.testDB = testDB
-	db.DB = testDB
-	logrus.Info("Test database initialized...")
+func monthPtr(dateStr string) *subscription.Month {
+	m := month(dateStr)
+	return &m
+}
 
-	err = suite.testDB.AutoMigrate(&subscription.Subscription{})
-	if err != nil {
-		logrus.Fatalf("Could not migrate database: %s", err)
+func (suite *HandlersTestSuite) SetupTest() {
+	suite.store = suite.newStore(suite.T())
+	cfg := config.Config{
+		Admin: config.Admin{Token: testAdminToken},
+		Bulk:  config.Bulk{MaxOperations: testMaxBulkOperations},
 	}
+	h := New(suite.store, suite.store, suite.store, cfg)
 
 	suite.app = fiber.New()
-	suite.app.Post("/api/v1/subscriptions", CreateSubscription)
-	suite.app.Get("/api/v1/subscriptions", GetSubscriptions)
-	suite.app.Put("/api/v1/subscriptions", UpdateSubscription)
-	suite.app.Delete("/api/v1/subscriptions", DeleteSubscription)
-	suite.app.Get("/api/v1/subscriptions/calculate", CalculateTotalCost)
-
-	go func() {
-		suite.app.Listen(":8081")
-	}()
-
-	time.Sleep(100 * time.Millisecond)
-}
-
-func (suite *HandlersTestSuite) TearDownSuite() {
-	sqlDB, _ := suite.testDB.DB()
-	sqlDB.Close()
-
-	if suite.pool != nil && suite.resource != nil {
-		if err := suite.pool.Purge(suite.resource); err != nil {
-			logrus.Fatalf("Couldn't purge resource: %s", err)
-		}
-	}
+	suite.app.Use(middleware.RequestID())
+	suite.app.Use(tracing.Middleware())
+	suite.app.Use(middleware.Actor(testAdminToken, testGatewaySecret))
+	RegisterRoutes(suite.app, h, cfg)
 }
 
-func (suite *HandlersTestSuite) SetupTest() {
-	if suite.testDB != nil {
-		suite.testDB.Where("1 = 1").Delete(&subscription.Subscription{})
-	}
+// create stores sub directly, bypassing the API.
+func (suite *HandlersTestSuite) create(sub *subscription.Subscription) error {
+	return suite.store.Create(context.Background(), sub)
 }
 
 func (suite *HandlersTestSuite) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
@@ -150,14 +99,14 @@ func (suite *HandlersTestSuite) makeRequest(method, endpoint string, body interf
 		reqBody = bytes.NewBuffer(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, "http://localhost:8081"+endpoint, reqBody)
+	req, err := http.NewRequest(method, endpoint, reqBody)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.AdminTokenHeader, testAdminToken)
 
-	client := &http.Client{}
-	return client.Do(req)
+	return suite.app.Test(req, -1)
 }
 
 func TestMain(m *testing.M) {
@@ -166,7 +115,26 @@ func TestMain(m *testing.M) {
 }
 
 func TestHandlersSuite(t *testing.T) {
-	suite.Run(t, new(HandlersTestSuite))
+	suite.Run(t, &HandlersTestSuite{newStore: func(*testing.T) db.Store {
+		return db.NewMemoryRepository()
+	}})
+}
+
+func TestHandlersSuite_SQLite(t *testing.T) {
+	suite.Run(t, &HandlersTestSuite{newStore: func(t *testing.T) db.Store {
+		conn, err := db.Open(config.Database{Driver: db.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+		if err != nil {
+			t.Fatalf("Could not open database: %s", err)
+		}
+		migrator, err := db.NewMigrator(conn)
+		if err != nil {
+			t.Fatalf("Could not load migrations: %s", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("Could not migrate database: %s", err)
+		}
+		return db.NewGormRepository(conn)
+	}})
 }
 
 func (suite *HandlersTestSuite) TestCreateSubscription_Success() {
@@ -175,7 +143,7 @@ func (suite *HandlersTestSuite) TestCreateSubscription_Success() {
 		ServiceName: "Test Yandex",
 		Price:       900,
 		UserId:      uuid.New(),
-		StartDate:   "01-2025",
+		StartDate:   month("01-2025"),
 	}
 
 	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
@@ -197,7 +165,7 @@ func (suite *HandlersTestSuite) TestCreateSubscription_Success() {
 	assert.Equal(suite.T(), "Test Yandex", createdSub.ServiceName)
 	assert.Equal(suite.T(), 900, createdSub.Price)
 	assert.Equal(suite.T(), sub.UserId, createdSub.UserId)
-	assert.Equal(suite.T(), "01-2025", createdSub.StartDate)
+	assert.Equal(suite.T(), "01-2025", createdSub.StartDate.String())
 }
 
 func (suite *HandlersTestSuite) TestCreateSubscription_InvalidJSON() {
@@ -216,17 +184,48 @@ func (suite *HandlersTestSuite) TestCreateSubscription_InvalidJSON() {
 
 func (suite *HandlersTestSuite) TestCreateSubscription_InvalidDateFormat() {
 
-	sub := subscription.Subscription{
-		ServiceName: "Test Yandex",
-		Price:       900,
-		UserId:      uuid.New(),
-		StartDate:   "15-01-2025",
+	sub := map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        900,
+		"user_id":      uuid.New(),
+		"start_date":   "15-01-2025",
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
+
+	assert.NoError(suite.T(), err)
+	var p problem.Problem
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&p))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+	if assert.Len(suite.T(), p.Errors, 1) {
+		assert.Equal(suite.T(), "start_date", p.Errors[0].Field)
+		assert.Equal(suite.T(), "format", p.Errors[0].Rule)
+		assert.Equal(suite.T(), "15-01-2025", p.Errors[0].Value)
+	}
+}
+
+func (suite *HandlersTestSuite) TestCreateSubscription_InvalidUserID() {
+
+	sub := map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        900,
+		"user_id":      "1234",
+		"start_date":   "01-2025",
 	}
 
 	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
 
 	assert.NoError(suite.T(), err)
+	var p problem.Problem
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&p))
+	resp.Body.Close()
 	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+	if assert.Len(suite.T(), p.Errors, 1) {
+		assert.Equal(suite.T(), "user_id", p.Errors[0].Field)
+		assert.Equal(suite.T(), "format", p.Errors[0].Rule)
+		assert.Equal(suite.T(), "1234", p.Errors[0].Value)
+	}
 }
 
 func (suite *HandlersTestSuite) TestGetSubscriptions_All() {
@@ -235,17 +234,17 @@ func (suite *HandlersTestSuite) TestGetSubscriptions_All() {
 		ServiceName: "Test Yandex",
 		Price:       900,
 		UserId:      uuid.New(),
-		StartDate:   "01-2025",
+		StartDate:   month("01-2025"),
 	}
 	sub2 := subscription.Subscription{
 		ServiceName: "Test Yandex 2",
 		Price:       600,
 		UserId:      uuid.New(),
-		StartDate:   "02-2025",
+		StartDate:   month("02-2025"),
 	}
 
-	suite.testDB.Create(&sub1)
-	suite.testDB.Create(&sub2)
+	suite.create(&sub1)
+	suite.create(&sub2)
 
 	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions", nil)
 	assert.NoError(suite.T(), err)
@@ -258,11 +257,12 @@ func (suite *HandlersTestSuite) TestGetSubscriptions_All() {
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(suite.T(), err)
 
-	var subs []subscription.Subscription
-	err = json.Unmarshal(body, &subs)
+	var page SubscriptionsPage
+	err = json.Unmarshal(body, &page)
 
 	assert.NoError(suite.T(), err)
-	assert.Len(suite.T(), subs, 2)
+	assert.Len(suite.T(), page.Items, 2)
+	assert.False(suite.T(), page.Pagination.HasMore)
 }
 
 func (suite *HandlersTestSuite) TestGetSubscriptions_All_Zero() {
@@ -278,11 +278,11 @@ func (suite *HandlersTestSuite) TestGetSubscriptions_All_Zero() {
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(suite.T(), err)
 
-	var subs []subscription.Subscription
-	err = json.Unmarshal(body, &subs)
+	var page SubscriptionsPage
+	err = json.Unmarshal(body, &page)
 
 	assert.NoError(suite.T(), err)
-	assert.Len(suite.T(), subs, 0)
+	assert.Len(suite.T(), page.Items, 0)
 }
 
 func (suite *HandlersTestSuite) TestGetSubscriptions_ByID_Found() {
@@ -291,10 +291,10 @@ func (suite *HandlersTestSuite) TestGetSubscriptions_ByID_Found() {
 		ServiceName: "Test Yandex",
 		Price:       900,
 		UserId:      uuid.New(),
-		StartDate:   "01-2025",
+		StartDate:   month("01-2025"),
 	}
 
-	suite.testDB.Create(&sub)
+	suite.create(&sub)
 
 	resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions?id=%s", sub.ID.String()), nil)
 	assert.NoError(suite.T(), err)
@@ -315,7 +315,7 @@ func (suite *HandlersTestSuite) TestGetSubscriptions_ByID_Found() {
 	assert.Equal(suite.T(), "Test Yandex", returnedSub.ServiceName)
 	assert.Equal(suite.T(), 900, returnedSub.Price)
 	assert.Equal(suite.T(), sub.UserId, returnedSub.UserId)
-	assert.Equal(suite.T(), "01-2025", returnedSub.StartDate)
+	assert.Equal(suite.T(), "01-2025", returnedSub.StartDate.String())
 }
 
 func (suite *HandlersTestSuite) TestGetSubscriptions_ByID_NotFound() {
@@ -333,10 +333,10 @@ func (suite *HandlersTestSuite) TestUpdateSubscription_Success() {
 		ServiceName: "Test Yandex",
 		Price:       900,
 		UserId:      uuid.New(),
-		StartDate:   "01-2025",
+		StartDate:   month("01-2025"),
 	}
-	result := suite.testDB.Create(&sub)
-	assert.NoError(suite.T(), result.Error)
+	err := suite.create(&sub)
+	assert.NoError(suite.T(), err)
 	assert.NotEqual(suite.T(), uuid.Nil, sub.ID)
 
 	updateReq := map[string]interface{}{
@@ -362,7 +362,7 @@ func (suite *HandlersTestSuite) TestUpdateSubscription_Success() {
 
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 100, updatedSub.Price)
-	assert.Equal(suite.T(), "02-2025", updatedSub.StartDate)
+	assert.Equal(suite.T(), "02-2025", updatedSub.StartDate.String())
 
 }
 
@@ -372,7 +372,7 @@ func (suite *HandlersTestSuite) TestUpdateSubscription_NotFound() {
 		ServiceName: "Test Yandex",
 		Price:       100,
 		UserId:      uuid.New(),
-		StartDate:   "02-2025",
+		StartDate:   month("02-2025"),
 	}
 
 	resp, err := suite.makeRequest("PUT", fmt.Sprintf("/api/v1/subscriptions?id=%s", uuid.New().String()), updatedData)
@@ -390,17 +390,16 @@ func (suite *HandlersTestSuite) TestDeleteSubscription_Success() {
 		ServiceName: "Test Yandex",
 		Price:       900,
 		UserId:      uuid.New(),
-		StartDate:   "01-2025",
+		StartDate:   month("01-2025"),
 	}
-	suite.testDB.Create(&sub)
+	suite.create(&sub)
 
 	resp, err := suite.makeRequest("DELETE", fmt.Sprintf("/api/v1/subscriptions?id=%s", sub.ID.String()), nil)
 	assert.NoError(suite.T(), err)
 	defer resp.Body.Close()
 
-	var count int64
-	suite.testDB.Model(&subscription.Subscription{}).Where("id = ?", sub.ID).Count(&count)
-	assert.Equal(suite.T(), int64(0), count)
+	_, err = suite.store.Get(context.Background(), sub.ID, false)
+	assert.ErrorIs(suite.T(), err, db.ErrNotFound)
 }
 
 func (suite *HandlersTestSuite) TestDeleteSubscription_NotFound() {
@@ -411,15 +410,556 @@ func (suite *HandlersTestSuite) TestDeleteSubscription_NotFound() {
 	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
 }
 
+func (suite *HandlersTestSuite) TestRestoreSubscription() {
+	sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 900, UserId: uuid.New(), StartDate: month("01-2025")}
+	suite.create(&sub)
+	path := fmt.Sprintf("/api/v1/subscriptions/%s", sub.ID)
+
+	resp, err := suite.makeRequest("POST", path+"/restore", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusConflict, resp.StatusCode)
+
+	resp, err = suite.makeRequest("DELETE", path, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("GET", path, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+
+	resp, err = suite.makeRequest("POST", path+"/restore", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var restored subscription.Subscription
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&restored))
+	assert.Equal(suite.T(), sub.ID, restored.ID)
+	assert.Nil(suite.T(), restored.DeletedAt)
+
+	resp, err = suite.makeRequest("POST", fmt.Sprintf("/api/v1/subscriptions/%s/restore", uuid.New()), nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestIncludeDeleted() {
+	userId := uuid.New()
+	kept := subscription.Subscription{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")}
+	deleted := subscription.Subscription{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")}
+	suite.create(&kept)
+	suite.create(&deleted)
+	assert.NoError(suite.T(), suite.store.Delete(context.Background(), deleted.ID))
+
+	list := func(query string) SubscriptionsPage {
+		resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions?user_id=%s%s", userId, query), nil)
+		assert.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		var page SubscriptionsPage
+		assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+		return page
+	}
+	assert.Len(suite.T(), list("").Items, 1)
+	assert.Len(suite.T(), list("&include_deleted=true").Items, 2)
+
+	total := func(query string) float64 {
+		resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/calculate?user_id=%s%s", userId, query), nil)
+		assert.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		var result SuccessCostResponse
+		assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&result))
+		return result.Total
+	}
+	assert.Equal(suite.T(), 100.0, total(""))
+	assert.Equal(suite.T(), 300.0, total("&include_deleted=true"))
+
+	resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/%s?include_deleted=true", deleted.ID), nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("GET", "/api/v1/subscriptions?include_deleted=maybe", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+
+	for _, endpoint := range []string{
+		"/api/v1/subscriptions?include_deleted=true",
+		"/api/v1/subscriptions/calculate?include_deleted=true",
+		fmt.Sprintf("/api/v1/subscriptions/%s?include_deleted=true", deleted.ID),
+	} {
+		req, err := http.NewRequest("GET", endpoint, nil)
+		assert.NoError(suite.T(), err)
+
+		resp, err := suite.app.Test(req, -1)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusForbidden, resp.StatusCode, endpoint)
+	}
+}
+
+// noAudit is an audit log predating the subscriptions of the store.
+type noAudit struct{}
+
+func (noAudit) ListAudit(context.Context, db.AuditFilter, int, *db.ListCursor) ([]audit.Entry, error) {
+	return nil, nil
+}
+
+func (suite *HandlersTestSuite) TestSubscriptionHistory_BeforeAuditLog() {
+	sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: uuid.New(), StartDate: month("01-2024")}
+	assert.NoError(suite.T(), suite.create(&sub))
+
+	h := New(suite.store, noAudit{}, suite.store, config.Config{})
+	app := fiber.New()
+	app.Get("/subscriptions/:id/history", h.GetSubscriptionHistory)
+
+	resp, err := app.Test(httptest.NewRequest("GET", fmt.Sprintf("/subscriptions/%s/history", sub.ID), nil))
+	assert.NoError(suite.T(), err)
+	var page AuditPage
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.NotNil(suite.T(), page.Items)
+	assert.Empty(suite.T(), page.Items)
+
+	resp, err = app.Test(httptest.NewRequest("GET", fmt.Sprintf("/subscriptions/%s/history", uuid.New()), nil))
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestSubscriptionHistory() {
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        400,
+		"user_id":      uuid.New(),
+		"start_date":   "01-2025",
+	})
+	assert.NoError(suite.T(), err)
+	var sub subscription.Subscription
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&sub))
+	resp.Body.Close()
+	path := fmt.Sprintf("/api/v1/subscriptions/%s", sub.ID)
+
+	req, err := http.NewRequest("PATCH", path, bytes.NewBufferString(`{"service_name": "Test Google"}`))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.ActorHeader, "support@example.com")
+	req.Header.Set(middleware.GatewaySecretHeader, testGatewaySecret)
+	resp, err = suite.app.Test(req, -1)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("DELETE", path, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+
+	resp, err = suite.makeRequest("GET", path+"/history?limit=2", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var page AuditPage
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+	assert.Len(suite.T(), page.Items, 2)
+	assert.True(suite.T(), page.Pagination.HasMore)
+	if len(page.Items) == 2 {
+		assert.Equal(suite.T(), audit.Create, page.Items[0].Action)
+		assert.Equal(suite.T(), "admin", page.Items[0].Actor)
+		assert.Equal(suite.T(), audit.Update, page.Items[1].Action)
+		assert.Equal(suite.T(), "support@example.com", page.Items[1].Actor)
+		assert.Equal(suite.T(), audit.Changes{{Field: "service_name", Old: "Test Yandex", New: "Test Google"}}, page.Items[1].Changes)
+	}
+
+	resp, err = suite.makeRequest("GET", path+"/history?cursor="+page.Pagination.NextCursor, nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+	assert.Len(suite.T(), page.Items, 1)
+	assert.False(suite.T(), page.Pagination.HasMore)
+
+	resp, err = suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/%s/history", uuid.New()), nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestAuditLog() {
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: month("02-2024")},
+	}
+	for i := range subs {
+		suite.create(&subs[i])
+	}
+	assert.NoError(suite.T(), suite.store.Delete(context.Background(), subs[1].ID))
+
+	list := func(query string) AuditPage {
+		resp, err := suite.makeRequest("GET", "/api/v1/admin/audit"+query, nil)
+		assert.NoError(suite.T(), err)
+		defer resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		var page AuditPage
+		assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+		return page
+	}
+
+	assert.Len(suite.T(), list("").Items, 3)
+	assert.Len(suite.T(), list("?action=delete").Items, 1)
+	assert.Len(suite.T(), list("?subscription_id="+subs[0].ID.String()).Items, 1)
+	assert.Len(suite.T(), list("?actor=system").Items, 3)
+	assert.Empty(suite.T(), list("?actor=admin").Items)
+	assert.Empty(suite.T(), list("?since=2999-01-01T00:00:00Z").Items)
+
+	for _, query := range []string{"?action=rename", "?subscription_id=42", "?since=yesterday"} {
+		resp, err := suite.makeRequest("GET", "/api/v1/admin/audit"+query, nil)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, query)
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/admin/audit", nil)
+	assert.NoError(suite.T(), err)
+	resp, err := suite.app.Test(req, -1)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+// bulk sends a bulk request and decodes the response.
+func (suite *HandlersTestSuite) bulk(req BulkRequest) (int, BulkResponse) {
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions/bulk", req)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	var result BulkResponse
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, result
+}
+
+func (suite *HandlersTestSuite) TestBulk_Atomic() {
+	existing := subscription.Subscription{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2025")}
+	suite.create(&existing)
+	price := 150
+	userId := uuid.New()
+
+	status, result := suite.bulk(BulkRequest{Operations: []BulkOperation{
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("01-2025")}},
+		{Op: "update", ID: &existing.ID, Changes: &UpdateSubscriptionRequest{Price: &price}},
+		{Op: "delete", ID: &existing.ID},
+	}})
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), "atomic", result.Mode)
+	assert.Equal(suite.T(), 3, result.Succeeded)
+	assert.Zero(suite.T(), result.Failed)
+	if assert.NotNil(suite.T(), result.Results[0].Subscription) {
+		assert.Equal(suite.T(), "RUB", result.Results[0].Subscription.Currency)
+	}
+	_, err := suite.store.Get(context.Background(), existing.ID, false)
+	assert.ErrorIs(suite.T(), err, db.ErrNotFound)
+
+	// The missing subscription fails the request, the create before it is rolled back.
+	missing := uuid.New()
+	status, result = suite.bulk(BulkRequest{Mode: "atomic", Operations: []BulkOperation{
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Yahoo", Price: 300, UserId: userId, StartDate: month("01-2025")}},
+		{Op: "delete", ID: &missing},
+	}})
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, status)
+	assert.Equal(suite.T(), 2, result.Failed)
+	assert.Equal(suite.T(), http.StatusFailedDependency, result.Results[0].Status)
+	assert.Nil(suite.T(), result.Results[0].Subscription)
+	assert.Equal(suite.T(), http.StatusNotFound, result.Results[1].Status)
+
+	// Invalid operations are reported without touching the store.
+	status, result = suite.bulk(BulkRequest{Operations: []BulkOperation{
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Yahoo", Price: 300, UserId: userId, StartDate: month("01-2025")}},
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Yahoo", UserId: userId, StartDate: month("01-2025")}},
+		{Op: "rename", ID: &missing},
+		{Op: "update", Changes: &UpdateSubscriptionRequest{Price: &price}},
+	}})
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, status)
+	assert.Equal(suite.T(), http.StatusFailedDependency, result.Results[0].Status)
+	if assert.NotNil(suite.T(), result.Results[1].Error) {
+		assert.Equal(suite.T(), "Validation failed", result.Results[1].Error.Title)
+		assert.Equal(suite.T(), "price", result.Results[1].Error.Errors[0].Field)
+	}
+	assert.Equal(suite.T(), http.StatusBadRequest, result.Results[2].Status)
+	assert.Equal(suite.T(), http.StatusBadRequest, result.Results[3].Status)
+
+	subs, err := suite.store.List(context.Background(), db.SubscriptionFilter{UserId: &userId}, db.ListOptions{Limit: 10, Sort: "created_at", Order: "asc"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), subs, 1)
+}
+
+func (suite *HandlersTestSuite) TestBulk_BestEffort() {
+	userId := uuid.New()
+	missing := uuid.New()
+
+	status, result := suite.bulk(BulkRequest{Mode: "best_effort", Operations: []BulkOperation{
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("01-2025")}},
+		{Op: "create", Subscription: &subscription.Subscription{ServiceName: "Test Yahoo", Price: -1, UserId: userId, StartDate: month("01-2025")}},
+		{Op: "delete", ID: &missing},
+	}})
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), 1, result.Succeeded)
+	assert.Equal(suite.T(), 2, result.Failed)
+	assert.Equal(suite.T(), http.StatusOK, result.Results[0].Status)
+	assert.NotNil(suite.T(), result.Results[0].ID)
+	assert.Equal(suite.T(), http.StatusBadRequest, result.Results[1].Status)
+	assert.Equal(suite.T(), http.StatusNotFound, result.Results[2].Status)
+
+	subs, err := suite.store.List(context.Background(), db.SubscriptionFilter{UserId: &userId}, db.ListOptions{Limit: 10, Sort: "created_at", Order: "asc"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), subs, 1)
+}
+
+func (suite *HandlersTestSuite) TestBulk_InvalidRequest() {
+	tooMany := make([]BulkOperation, testMaxBulkOperations+1)
+	for i := range tooMany {
+		tooMany[i] = BulkOperation{Op: "delete", ID: &uuid.UUID{}}
+	}
+
+	for _, req := range []BulkRequest{
+		{},
+		{Operations: tooMany},
+		{Mode: "eventually", Operations: tooMany[:1]},
+	} {
+		resp, err := suite.makeRequest("POST", "/api/v1/subscriptions/bulk", req)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// upload posts content as the file field of a multipart form.
+func (suite *HandlersTestSuite) upload(endpoint, filename, content string) *http.Response {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	assert.NoError(suite.T(), err)
+	_, err = part.Write([]byte(content))
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), form.Close())
+
+	req, err := http.NewRequest("POST", endpoint, &body)
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := suite.app.Test(req, -1)
+	assert.NoError(suite.T(), err)
+	return resp
+}
+
+func (suite *HandlersTestSuite) TestImportSubscriptions() {
+	userId := uuid.New()
+	file := fmt.Sprintf("service_name,price,user_id,start_date\nNetflix,400,%[1]s,01-2025\nSpotify,199,%[1]s,02-2025\n", userId)
+	count := func() int {
+		subs, err := suite.store.List(context.Background(), db.SubscriptionFilter{UserId: &userId}, db.ListOptions{Limit: 10, Sort: "created_at", Order: "asc"})
+		assert.NoError(suite.T(), err)
+		return len(subs)
+	}
+
+	resp := suite.upload("/api/v1/subscriptions/import?dry_run=true", "subs.csv", file)
+	var report importer.Report
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.True(suite.T(), report.DryRun)
+	assert.Equal(suite.T(), 2, report.Rows)
+	assert.Zero(suite.T(), count())
+
+	resp = suite.upload("/api/v1/subscriptions/import", "subs.csv", file)
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, report.Imported)
+	assert.Equal(suite.T(), 2, count())
+
+	resp = suite.upload("/api/v1/subscriptions/import", "subs.csv", file+"Yandex,0,"+userId.String()+",01-2025\n")
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(suite.T(), []importer.RowError{{Row: 4, Column: "price", Message: "is required"}}, report.Errors)
+	assert.Equal(suite.T(), 2, count())
+
+	for name, content := range map[string]string{"subs.csv": "owner\n", "subs.ods": file} {
+		resp = suite.upload("/api/v1/subscriptions/import", name, content)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions/import", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestExportSubscriptions() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Netflix", Price: 400, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("06-2025")},
+		{ServiceName: "Spotify, Premium", Price: 199, UserId: userId, StartDate: month("02-2025")},
+		{ServiceName: "Yandex", Price: 300, UserId: uuid.New(), StartDate: month("03-2025")},
+	}
+	for i := range subs {
+		assert.NoError(suite.T(), suite.create(&subs[i]))
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/export?sort=price&user_id="+userId.String(), nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(suite.T(), `attachment; filename="subscriptions.csv"`, resp.Header.Get("Content-Disposition"))
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), records, 3) {
+		assert.Equal(suite.T(), subscriptionColumns, records[0])
+		assert.Equal(suite.T(), []string{"Spotify, Premium", "199", "RUB", "monthly", "02-2025", ""},
+			[]string{records[1][1], records[1][2], records[1][3], records[1][4], records[1][6], records[1][7]})
+		assert.Equal(suite.T(), subs[0].ID.String(), records[2][0])
+		assert.Equal(suite.T(), "06-2025", records[2][7])
+	}
+
+	resp, err = suite.makeRequest("GET", "/api/v1/subscriptions/export?format=jsonl&sort=price", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var exported []subscription.Subscription
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		var sub subscription.Subscription
+		assert.NoError(suite.T(), decoder.Decode(&sub))
+		exported = append(exported, sub)
+	}
+	resp.Body.Close()
+	if assert.Len(suite.T(), exported, 3) {
+		assert.Equal(suite.T(), subs[2].ID, exported[1].ID)
+	}
+
+	for _, query := range []string{"format=xml", "sort=name", "user_id=bad"} {
+		resp, err = suite.makeRequest("GET", "/api/v1/subscriptions/export?"+query, nil)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (suite *HandlersTestSuite) TestExportSubscriptions_Batches() {
+	total := exportBatchSize + 2
+	err := suite.store.Transaction(context.Background(), func(tx db.SubscriptionRepository) error {
+		for i := 0; i < total; i++ {
+			sub := subscription.Subscription{ServiceName: "Netflix", Price: i + 1, UserId: uuid.New(), StartDate: month("01-2025")}
+			if err := tx.Create(context.Background(), &sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/export?sort=price&order=desc", nil)
+	assert.NoError(suite.T(), err)
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(suite.T(), err)
+
+	if assert.Len(suite.T(), records, total+1) {
+		for i, record := range records[1:] {
+			assert.Equal(suite.T(), strconv.Itoa(total-i), record[2])
+		}
+	}
+}
+
+func (suite *HandlersTestSuite) TestExportCostBreakdown() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Netflix", Price: 400, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("03-2025")},
+		{ServiceName: "Spotify", Price: 200, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("02-2025")},
+	}
+	for i := range subs {
+		assert.NoError(suite.T(), suite.create(&subs[i]))
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate/breakdown/export?group_by=both&start_date=01-2025&end_date=12-2025", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), [][]string{
+		breakdownColumns,
+		{"Netflix", userId.String(), "1200.00", "RUB"},
+		{"Spotify", userId.String(), "400.00", "RUB"},
+	}, records)
+
+	resp, err = suite.makeRequest("GET", "/api/v1/subscriptions/calculate/breakdown/export?format=jsonl&group_by=user_id&start_date=01-2025&end_date=12-2025", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	var record map[string]interface{}
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&record))
+	resp.Body.Close()
+	assert.Equal(suite.T(), map[string]interface{}{"user_id": userId.String(), "total": 1600.0, "currency": "RUB"}, record)
+
+	resp, err = suite.makeRequest("GET", "/api/v1/subscriptions/calculate/breakdown/export?group_by=month", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestGetUserStatement() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Spotify", Price: 200, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("02-2025")},
+		{ServiceName: "<Netflix>", Price: 400, UserId: userId, StartDate: month("01-2025")},
+		{ServiceName: "Yandex", Price: 300, UserId: uuid.New(), StartDate: month("01-2025")},
+	}
+	for i := range subs {
+		assert.NoError(suite.T(), suite.create(&subs[i]))
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/users/"+userId.String()+"/statement?start_date=01-2025&end_date=03-2025", nil)
+	assert.NoError(suite.T(), err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), fiber.MIMETextHTMLCharsetUTF8, resp.Header.Get("Content-Type"))
+
+	html := string(body)
+	assert.Contains(suite.T(), html, userId.String())
+	assert.Contains(suite.T(), html, "&lt;Netflix&gt;")
+	assert.Contains(suite.T(), html, "1200.00")
+	assert.Contains(suite.T(), html, "400.00")
+	assert.Contains(suite.T(), html, "1600.00")
+	assert.NotContains(suite.T(), html, "Yandex")
+	assert.Less(suite.T(), strings.Index(html, "&lt;Netflix&gt;"), strings.Index(html, "Spotify"))
+
+	resp, err = suite.makeRequest("GET", "/api/v1/users/not-a-uuid/statement", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
 func (suite *HandlersTestSuite) TestCalcTotalCost_NoFilter() {
 	subs := []subscription.Subscription{
-		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: "03-2024"},
+		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
 	}
 
 	for _, sub := range subs {
-		suite.testDB.Create(&sub)
+		suite.create(&sub)
 	}
 
 	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate", nil)
@@ -431,29 +971,28 @@ func (suite *HandlersTestSuite) TestCalcTotalCost_NoFilter() {
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(suite.T(), err)
 
-	var result map[string]int
+	var result SuccessCostResponse
 	err = json.Unmarshal(body, &result)
 	assert.NoError(suite.T(), err)
 
-	assert.Equal(suite.T(), 600, result["total"])
+	assert.Equal(suite.T(), 600.0, result.Total)
 
 }
 
 func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserId() {
 	userId := uuid.New()
 	subs := []subscription.Subscription{
-		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: "03-2024"},
-		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: "03-2024"},
+		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
 	}
 
 	for _, sub := range subs {
-		result := suite.testDB.Create(&sub)
-		if result.Error != nil {
-			suite.T().Fatalf("Failed to create subscription: %v", result.Error)
+		if err := suite.create(&sub); err != nil {
+			suite.T().Fatalf("Failed to create subscription: %v", err)
 		}
 	}
 
@@ -472,22 +1011,22 @@ func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserId() {
 
 	err = json.Unmarshal(body, &result)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), 606, result.Total)
+	assert.Equal(suite.T(), 606.0, result.Total)
 }
 
 func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserIdStartDate() {
 	userId := uuid.New()
 	subs := []subscription.Subscription{
-		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: "03-2024"},
-		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: "03-2024"},
+		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
 	}
 
 	for _, sub := range subs {
-		suite.testDB.Create(&sub)
+		suite.create(&sub)
 	}
 
 	resp, err := suite.makeRequest(
@@ -505,29 +1044,29 @@ func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserIdStartDate() {
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(suite.T(), err)
 
-	var result map[string]int
+	var result SuccessCostResponse
 	err = json.Unmarshal(body, &result)
 	assert.NoError(suite.T(), err)
 
-	assert.Equal(suite.T(), 505, result["total"])
+	assert.Equal(suite.T(), 505.0, result.Total)
 
 }
 
 func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserIdStartEndDate() {
 	userId := uuid.New()
 	subs := []subscription.Subscription{
-		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: "03-2024"},
-		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: "01-2024"},
-		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: "02-2024"},
-		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: "03-2024"},
-		{ServiceName: "Test Yahoo", Price: 404, UserId: userId, StartDate: "04-2024"},
-		{ServiceName: "Test Yahoo", Price: 505, UserId: userId, StartDate: "05-2024"},
+		{ServiceName: "Test Yandex", Price: 100, UserId: uuid.New(), StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: uuid.New(), StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yandex", Price: 101, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Google", Price: 202, UserId: userId, StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Yahoo", Price: 303, UserId: userId, StartDate: month("03-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yahoo", Price: 404, UserId: userId, StartDate: month("04-2024"), EndDate: monthPtr("04-2024")},
+		{ServiceName: "Test Yahoo", Price: 505, UserId: userId, StartDate: month("05-2024"), EndDate: monthPtr("05-2024")},
 	}
 
 	for _, sub := range subs {
-		suite.testDB.Create(&sub)
+		suite.create(&sub)
 	}
 
 	resp, err := suite.makeRequest(
@@ -547,10 +1086,818 @@ func (suite *HandlersTestSuite) TestCalcTotalCost_Filter_UserIdStartEndDate() {
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(suite.T(), err)
 
-	var result map[string]int
+	var result SuccessCostResponse
 	err = json.Unmarshal(body, &result)
 	assert.NoError(suite.T(), err)
 
-	assert.Equal(suite.T(), 909, result["total"])
+	assert.Equal(suite.T(), 1414.0, result.Total)
 
 }
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_MultiMonth() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("01-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("06-2024"), EndDate: monthPtr("08-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: userId, StartDate: month("01-2025")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest(
+		"GET",
+		fmt.Sprintf(
+			"/api/v1/subscriptions/calculate?user_id=%s&start_date=%s&end_date=%s",
+			userId.String(), "03-2024", "12-2024",
+		),
+		nil,
+	)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+
+	var result SuccessCostResponse
+	err = json.Unmarshal(body, &result)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), float64(100*10+200*3), result.Total)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_InvalidPeriod() {
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate?start_date=05-2024&end_date=01-2024", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_PeriodTooLong() {
+	for _, endpoint := range []string{
+		"/api/v1/subscriptions/calculate?start_date=01-1900&end_date=12-9999",
+		"/api/v1/subscriptions/calculate?end_date=12-9999",
+		"/api/v1/subscriptions/calculate/breakdown?start_date=01-1900&end_date=12-9999",
+	} {
+		resp, err := suite.makeRequest("GET", endpoint, nil)
+		assert.NoError(suite.T(), err)
+		var p problem.Problem
+		assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&p))
+		resp.Body.Close()
+
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, endpoint)
+		assert.Equal(suite.T(), fmt.Sprintf("period must not be longer than %d months", maxPeriodMonths), p.Detail, endpoint)
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate?start_date=01-2000&end_date=12-2099", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_FutureStartDate() {
+	userId := uuid.New()
+	start := subscription.CurrentMonth().AddMonths(3)
+	suite.create(&subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: userId, StartDate: start})
+
+	resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/calculate?user_id=%s&start_date=%s", userId, start), nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var result SuccessCostResponse
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(suite.T(), 400.0, result.Total)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_Tracing() {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 400, UserId: uuid.New(), StartDate: month("01-2025")}
+	assert.NoError(suite.T(), suite.create(&sub))
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate?start_date=01-2025&end_date=03-2025", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	spans := recorder.Ended()
+	if !assert.Len(suite.T(), spans, 2) {
+		return
+	}
+	calculation, request := spans[0], spans[1]
+	assert.Equal(suite.T(), "GET /api/v1/subscriptions/calculate", request.Name())
+	assert.Equal(suite.T(), "cost.aggregate", calculation.Name())
+	assert.Equal(suite.T(), request.SpanContext().SpanID(), calculation.Parent().SpanID())
+	assert.Contains(suite.T(), calculation.Attributes(), attribute.Int("cost.subscriptions", 1))
+}
+
+func (suite *HandlersTestSuite) TestCalcCostBreakdown_GroupByService() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yandex", Price: 150, UserId: uuid.New(), StartDate: month("02-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("03-2024"), EndDate: monthPtr("04-2024")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate/breakdown?start_date=01-2024&end_date=12-2024", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+
+	var result CostBreakdownResponse
+	err = json.Unmarshal(body, &result)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "service_name", result.GroupBy)
+	assert.Equal(suite.T(), 850.0, result.Total)
+	assert.Equal(suite.T(), []CostGroup{
+		{ServiceName: "Test Yandex", Total: 450},
+		{ServiceName: "Test Google", Total: 400},
+	}, result.Groups)
+}
+
+func (suite *HandlersTestSuite) TestCalcCostBreakdown_GroupByBoth() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("03-2024"), EndDate: monthPtr("04-2024")},
+		{ServiceName: "Test Google", Price: 500, UserId: uuid.New(), StartDate: month("03-2024"), EndDate: monthPtr("04-2024")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest(
+		"GET",
+		fmt.Sprintf("/api/v1/subscriptions/calculate/breakdown?group_by=both&user_id=%s&end_date=12-2024", userId.String()),
+		nil,
+	)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+
+	var result CostBreakdownResponse
+	err = json.Unmarshal(body, &result)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), 700.0, result.Total)
+	assert.Equal(suite.T(), []CostGroup{
+		{ServiceName: "Test Google", UserId: &userId, Total: 400},
+		{ServiceName: "Test Yandex", UserId: &userId, Total: 300},
+	}, result.Groups)
+}
+
+func (suite *HandlersTestSuite) TestCalcCostBreakdown_InvalidGroupBy() {
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate/breakdown?group_by=price", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestCalcMonthlyCost() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("12-2023")},
+		{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("02-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Yahoo", Price: 300, UserId: uuid.New(), StartDate: month("01-2024")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest(
+		"GET",
+		fmt.Sprintf("/api/v1/subscriptions/calculate/monthly?user_id=%s&start_date=01-2024&end_date=04-2024", userId.String()),
+		nil,
+	)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+
+	var result MonthlyCostResponse
+	err = json.Unmarshal(body, &result)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), 800.0, result.Total)
+	assert.Equal(suite.T(), []MonthlyCost{
+		{Month: month("01-2024"), Total: 100},
+		{Month: month("02-2024"), Total: 300},
+		{Month: month("03-2024"), Total: 300},
+		{Month: month("04-2024"), Total: 100},
+	}, result.Months)
+}
+
+func (suite *HandlersTestSuite) TestCalcMonthlyCost_MissingStart() {
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions/calculate/monthly?end_date=04-2024", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_BillingPeriods() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 9000, BillingPeriod: subscription.Yearly, UserId: userId, StartDate: month("03-2024")},
+		{ServiceName: "Test Google", Price: 300, BillingPeriod: subscription.Quarterly, UserId: userId, StartDate: month("01-2024")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	cases := []struct {
+		mode string
+		want float64
+	}{
+		{"accrual", 750*4 + 100*6},
+		{"cash", 9000 + 300*2},
+	}
+
+	for _, testCase := range cases {
+		resp, err := suite.makeRequest(
+			"GET",
+			fmt.Sprintf(
+				"/api/v1/subscriptions/calculate?user_id=%s&start_date=%s&end_date=%s&mode=%s",
+				userId.String(), "01-2024", "06-2024", testCase.mode,
+			),
+			nil,
+		)
+		assert.NoError(suite.T(), err)
+		defer resp.Body.Close()
+
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(suite.T(), err)
+
+		var result SuccessCostResponse
+		err = json.Unmarshal(body, &result)
+		assert.NoError(suite.T(), err)
+
+		assert.Equal(suite.T(), testCase.want, result.Total, testCase.mode)
+	}
+}
+
+func (suite *HandlersTestSuite) TestCreateSubscription_InvalidBillingPeriod() {
+	sub := map[string]interface{}{
+		"service_name":   "Test Yandex",
+		"price":          900,
+		"billing_period": "daily",
+		"user_id":        uuid.New(),
+		"start_date":     "01-2025",
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestEndDateBeforeStartDate() {
+	sub := map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        900,
+		"user_id":      uuid.New(),
+		"start_date":   "06-2025",
+		"end_date":     "01-2025",
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
+	assert.NoError(suite.T(), err)
+	var errorResp problem.Problem
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&errorResp))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(suite.T(), problem.ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(suite.T(), "Field 'end_date' must not be before start_date", errorResp.Detail)
+	assert.Equal(suite.T(), "/api/v1/subscriptions", errorResp.Instance)
+	assert.NotEmpty(suite.T(), errorResp.RequestID)
+	assert.Equal(suite.T(), resp.Header.Get(requestid.Header), errorResp.RequestID)
+	if assert.Len(suite.T(), errorResp.Errors, 1) {
+		assert.Equal(suite.T(), "end_date", errorResp.Errors[0].Field)
+		assert.Equal(suite.T(), "gtefield", errorResp.Errors[0].Rule)
+		assert.Equal(suite.T(), "01-2025", errorResp.Errors[0].Value)
+	}
+
+	stored := subscription.Subscription{ServiceName: "Test Yandex", Price: 900, UserId: uuid.New(), StartDate: month("06-2025"), EndDate: monthPtr("12-2025")}
+	assert.NoError(suite.T(), suite.create(&stored))
+	path := fmt.Sprintf("/api/v1/subscriptions/%s", stored.ID)
+
+	for _, changes := range []map[string]interface{}{
+		{"end_date": "05-2025"},
+		{"start_date": "01-2026"},
+	} {
+		resp, err = suite.makeRequest("PATCH", path, changes)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, changes)
+	}
+
+	resp, err = suite.makeRequest("PATCH", path, map[string]interface{}{"start_date": "01-2026", "end_date": "06-2026"})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestOverlappingSubscriptions() {
+	userId := uuid.New()
+	first := subscription.Subscription{ServiceName: "Test Yandex", Price: 900, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("06-2025")}
+	assert.NoError(suite.T(), suite.create(&first))
+
+	overlapping := map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        900,
+		"user_id":      userId,
+		"start_date":   "06-2025",
+	}
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", overlapping)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusConflict, resp.StatusCode)
+
+	overlapping["start_date"] = "07-2025"
+	resp, err = suite.makeRequest("POST", "/api/v1/subscriptions", overlapping)
+	assert.NoError(suite.T(), err)
+	var second subscription.Subscription
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&second))
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	secondPath := fmt.Sprintf("/api/v1/subscriptions/%s", second.ID)
+	resp, err = suite.makeRequest("PATCH", secondPath, map[string]interface{}{"start_date": "03-2025"})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusConflict, resp.StatusCode)
+
+	resp, err = suite.makeRequest("PATCH", secondPath, map[string]interface{}{"start_date": "03-2025", "allow_overlap": true})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	overlapping["start_date"] = "02-2025"
+	overlapping["service_name"] = "Test Google"
+	resp, err = suite.makeRequest("POST", "/api/v1/subscriptions", overlapping)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	firstPath := fmt.Sprintf("/api/v1/subscriptions/%s", first.ID)
+	resp, err = suite.makeRequest("DELETE", firstPath, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+
+	third := subscription.Subscription{ServiceName: "Test Yandex", Price: 900, UserId: userId, StartDate: month("01-2025"), EndDate: monthPtr("02-2025")}
+	assert.NoError(suite.T(), suite.create(&third))
+
+	resp, err = suite.makeRequest("POST", firstPath+"/restore", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusConflict, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestCalcTotalCost_TargetCurrency() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 900, Currency: "RUB", UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+		{ServiceName: "Test Netflix", Price: 10, Currency: "USD", UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("02-2024")},
+		{ServiceName: "Test Spotify", Price: 5, Currency: "EUR", UserId: userId, StartDate: month("01-2024"), EndDate: monthPtr("01-2024")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/calculate?user_id=%s&end_date=12-2024", userId.String()), nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, resp.StatusCode)
+
+	for _, rate := range []currency.Rate{{Currency: "USD", Rate: 90}, {Currency: "eur", Rate: 100}} {
+		resp, err := suite.makeRequest("PUT", "/api/v1/admin/rates", rate)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err = suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/calculate?user_id=%s&end_date=12-2024&target_currency=usd", userId.String()), nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+
+	var result SuccessCostResponse
+	err = json.Unmarshal(body, &result)
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "USD", result.Currency)
+	assert.InDelta(suite.T(), 35.56, result.Total, 0.001)
+}
+
+func (suite *HandlersTestSuite) TestCreateSubscription_InvalidCurrency() {
+	sub := map[string]interface{}{
+		"service_name": "Test Yandex",
+		"price":        900,
+		"currency":     "RUR",
+		"user_id":      uuid.New(),
+		"start_date":   "01-2025",
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestRates_RequireAdminToken() {
+	req, err := http.NewRequest("GET", "/api/v1/admin/rates", nil)
+	assert.NoError(suite.T(), err)
+
+	resp, err := suite.app.Test(req, -1)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestRates_Delete() {
+	resp, err := suite.makeRequest("PUT", "/api/v1/admin/rates", currency.Rate{Currency: "USD", Rate: 90})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+
+	resp, err = suite.makeRequest("DELETE", "/api/v1/admin/rates?currency=usd", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("DELETE", "/api/v1/admin/rates?currency=usd", nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestUpdateSubscription_PriceHistory() {
+	sub := map[string]interface{}{
+		"service_name": "Test Netflix",
+		"price":        500,
+		"user_id":      uuid.New(),
+		"start_date":   "01-2024",
+	}
+
+	resp, err := suite.makeRequest("POST", "/api/v1/subscriptions", sub)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	var created subscription.Subscription
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	assert.NoError(suite.T(), err)
+
+	updateReq := map[string]interface{}{
+		"price":                700,
+		"price_effective_from": "04-2024",
+	}
+	resp, err = suite.makeRequest("PUT", fmt.Sprintf("/api/v1/subscriptions?id=%s", created.ID.String()), updateReq)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/prices?id=%s", created.ID.String()), nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var history []subscription.PriceChange
+	err = json.NewDecoder(resp.Body).Decode(&history)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+	assert.Equal(suite.T(), 500, history[0].Price)
+	assert.Equal(suite.T(), "01-2024", history[0].EffectiveFrom.String())
+	assert.Equal(suite.T(), 700, history[1].Price)
+	assert.Equal(suite.T(), "04-2024", history[1].EffectiveFrom.String())
+
+	resp, err = suite.makeRequest(
+		"GET",
+		fmt.Sprintf("/api/v1/subscriptions/calculate?user_id=%s&start_date=01-2024&end_date=06-2024", created.UserId.String()),
+		nil,
+	)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	var result SuccessCostResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 500.0*3+700*3, result.Total)
+}
+
+func (suite *HandlersTestSuite) TestUpdateSubscription_EffectiveFromWithoutPrice() {
+	sub := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: uuid.New(), StartDate: month("01-2024")}
+	suite.create(&sub)
+
+	resp, err := suite.makeRequest("PATCH", fmt.Sprintf("/api/v1/subscriptions/%s", sub.ID), map[string]interface{}{
+		"price_effective_from": "04-2024",
+	})
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+
+	var p problem.Problem
+	assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&p))
+	if assert.Len(suite.T(), p.Errors, 1) {
+		assert.Equal(suite.T(), "price_effective_from", p.Errors[0].Field)
+		assert.Equal(suite.T(), "excluded_without", p.Errors[0].Rule)
+	}
+}
+
+func (suite *HandlersTestSuite) TestUpdateSubscription_FuturePriceTakesEffect() {
+	now := subscription.Now
+	defer func() { subscription.Now = now }()
+	subscription.Now = func() time.Time { return time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC) }
+
+	userId := uuid.New()
+	changed := subscription.Subscription{ServiceName: "Test Netflix", Price: 500, UserId: userId, StartDate: month("01-2025")}
+	other := subscription.Subscription{ServiceName: "Test Spotify", Price: 400, UserId: userId, StartDate: month("01-2025")}
+	assert.NoError(suite.T(), suite.create(&changed))
+	assert.NoError(suite.T(), suite.create(&other))
+
+	path := fmt.Sprintf("/api/v1/subscriptions/%s", changed.ID)
+	resp, err := suite.makeRequest("PATCH", path, map[string]interface{}{"price": 300, "price_effective_from": "06-2025"})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	check := func(price int, byPrice []uuid.UUID) {
+		resp, err := suite.makeRequest("GET", path, nil)
+		assert.NoError(suite.T(), err)
+		var got subscription.Subscription
+		assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&got))
+		resp.Body.Close()
+		assert.Equal(suite.T(), price, got.Price)
+
+		var listed []uuid.UUID
+		cursor := ""
+		for pages := 0; pages < 3; pages++ {
+			endpoint := fmt.Sprintf("/api/v1/subscriptions?user_id=%s&sort=price&limit=1", userId)
+			if cursor != "" {
+				endpoint += "&cursor=" + cursor
+			}
+			resp, err := suite.makeRequest("GET", endpoint, nil)
+			assert.NoError(suite.T(), err)
+			var page SubscriptionsPage
+			assert.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(&page))
+			resp.Body.Close()
+			for _, sub := range page.Items {
+				listed = append(listed, sub.ID)
+			}
+			if !page.Pagination.HasMore {
+				break
+			}
+			cursor = page.Pagination.NextCursor
+		}
+		assert.Equal(suite.T(), byPrice, listed)
+
+		resp, err = suite.makeRequest("GET", "/api/v1/subscriptions/export?sort=price&user_id="+userId.String(), nil)
+		assert.NoError(suite.T(), err)
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		resp.Body.Close()
+		assert.NoError(suite.T(), err)
+		if assert.Len(suite.T(), records, 3) {
+			exported := map[string]string{records[1][0]: records[1][2], records[2][0]: records[2][2]}
+			assert.Equal(suite.T(), strconv.Itoa(price), exported[changed.ID.String()])
+		}
+	}
+
+	check(500, []uuid.UUID{other.ID, changed.ID})
+
+	subscription.Now = func() time.Time { return time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC) }
+	check(300, []uuid.UUID{changed.ID, other.ID})
+}
+
+func (suite *HandlersTestSuite) TestGetPriceHistory_NotFound() {
+	resp, err := suite.makeRequest("GET", fmt.Sprintf("/api/v1/subscriptions/prices?id=%s", uuid.New().String()), nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestGetSubscriptions_Pagination() {
+	userId := uuid.New()
+	for i, price := range []int{500, 100, 400, 200, 300} {
+		sub := subscription.Subscription{
+			ServiceName: fmt.Sprintf("Test Service %d", i),
+			Price:       price,
+			UserId:      userId,
+			StartDate:   month("01-2024"),
+		}
+		suite.create(&sub)
+	}
+	suite.create(&subscription.Subscription{ServiceName: "Test Other", Price: 150, UserId: uuid.New(), StartDate: month("01-2024")})
+
+	var prices []int
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		endpoint := fmt.Sprintf("/api/v1/subscriptions?user_id=%s&sort=price&order=desc&limit=2", userId.String())
+		if cursor != "" {
+			endpoint += "&cursor=" + cursor
+		}
+
+		resp, err := suite.makeRequest("GET", endpoint, nil)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+		var page SubscriptionsPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		assert.NoError(suite.T(), err)
+
+		for _, sub := range page.Items {
+			prices = append(prices, sub.Price)
+		}
+
+		if !page.Pagination.HasMore {
+			break
+		}
+		cursor = page.Pagination.NextCursor
+	}
+
+	assert.Equal(suite.T(), []int{500, 400, 300, 200, 100}, prices)
+}
+
+func (suite *HandlersTestSuite) TestGetSubscriptions_DateFilter() {
+	userId := uuid.New()
+	subs := []subscription.Subscription{
+		{ServiceName: "Test Yandex", Price: 100, UserId: userId, StartDate: month("02-2024"), EndDate: monthPtr("03-2024")},
+		{ServiceName: "Test Google", Price: 200, UserId: userId, StartDate: month("01-2025")},
+	}
+
+	for _, sub := range subs {
+		suite.create(&sub)
+	}
+
+	resp, err := suite.makeRequest("GET", "/api/v1/subscriptions?start_date=12-2024", nil)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+
+	var page SubscriptionsPage
+	err = json.NewDecoder(resp.Body).Decode(&page)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), page.Items, 1)
+	assert.Equal(suite.T(), "Test Google", page.Items[0].ServiceName)
+}
+
+func (suite *HandlersTestSuite) TestGetSubscriptions_InvalidPagination() {
+	for _, query := range []string{"limit=0", "limit=501", "sort=user_id", "order=up", "cursor=garbage", "user_id=42"} {
+		resp, err := suite.makeRequest("GET", "/api/v1/subscriptions?"+query, nil)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (suite *HandlersTestSuite) TestPathRoutes() {
+	sub := subscription.Subscription{
+		ServiceName: "Test Yandex",
+		Price:       900,
+		UserId:      uuid.New(),
+		StartDate:   month("01-2025"),
+		EndDate:     monthPtr("12-2025"),
+	}
+	suite.create(&sub)
+	endpoint := fmt.Sprintf("/api/v1/subscriptions/%s", sub.ID.String())
+
+	resp, err := suite.makeRequest("GET", endpoint, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Empty(suite.T(), resp.Header.Get("Deprecation"))
+
+	resp, err = suite.makeRequest("PATCH", endpoint, map[string]interface{}{"service_name": "Test Yandex Plus"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var patched subscription.Subscription
+	err = json.NewDecoder(resp.Body).Decode(&patched)
+	resp.Body.Close()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Test Yandex Plus", patched.ServiceName)
+	assert.Equal(suite.T(), 900, patched.Price)
+	assert.NotNil(suite.T(), patched.EndDate)
+
+	replacement := map[string]interface{}{
+		"service_name": "Test Kinopoisk",
+		"price":        300,
+		"user_id":      sub.UserId,
+		"start_date":   "02-2025",
+	}
+	resp, err = suite.makeRequest("PUT", endpoint, replacement)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	var replaced subscription.Subscription
+	err = json.NewDecoder(resp.Body).Decode(&replaced)
+	resp.Body.Close()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), sub.ID, replaced.ID)
+	assert.Equal(suite.T(), "Test Kinopoisk", replaced.ServiceName)
+	assert.Equal(suite.T(), "02-2025", replaced.StartDate.String())
+	assert.Nil(suite.T(), replaced.EndDate)
+
+	resp, err = suite.makeRequest("PUT", endpoint, map[string]interface{}{"price": 300})
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = suite.makeRequest("DELETE", endpoint, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.makeRequest("GET", endpoint, nil)
+	assert.NoError(suite.T(), err)
+	resp.Body.Close()
+	assert.Equal(suite.T(), http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *HandlersTestSuite) TestMalformedID() {
+	requests := []struct {
+		method   string
+		endpoint string
+	}{
+		{"GET", "/api/v1/subscriptions/not-a-uuid"},
+		{"PATCH", "/api/v1/subscriptions/not-a-uuid"},
+		{"DELETE", "/api/v1/subscriptions/not-a-uuid"},
+		{"GET", "/api/v1/subscriptions/not-a-uuid/prices"},
+		{"GET", "/api/v1/subscriptions?id=not-a-uuid"},
+		{"PUT", "/api/v1/subscriptions?id=not-a-uuid"},
+		{"DELETE", "/api/v1/subscriptions?id=not-a-uuid"},
+	}
+
+	for _, request := range requests {
+		resp, err := suite.makeRequest(request.method, request.endpoint, map[string]interface{}{})
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+		assert.Equal(suite.T(), http.StatusBadRequest, resp.StatusCode, request.method+" "+request.endpoint)
+	}
+}
+
+func (suite *HandlersTestSuite) TestQueryIDRoutes_Deprecated() {
+	sub := subscription.Subscription{ServiceName: "Test Yandex", Price: 900, UserId: uuid.New(), StartDate: month("01-2025")}
+	suite.create(&sub)
+
+	tests := []struct {
+		method   string
+		endpoint string
+		body     interface{}
+		link     string
+	}{
+		{"GET", "/api/v1/subscriptions?id=%s", nil, `</api/v1/subscriptions/%s>; rel="successor-version"`},
+		{"GET", "/api/v1/subscriptions/prices?id=%s", nil, `</api/v1/subscriptions/%s/prices>; rel="successor-version"`},
+		// PUT /subscriptions/{id} replaces the subscription, the alias only
+		// updates the fields sent like PATCH does.
+		{"PUT", "/api/v1/subscriptions?id=%s", map[string]interface{}{"price": 1000}, `</api/v1/subscriptions/%s>; rel="successor-version"; method="PATCH"`},
+		{"DELETE", "/api/v1/subscriptions?id=%s", nil, `</api/v1/subscriptions/%s>; rel="successor-version"`},
+	}
+
+	for _, tt := range tests {
+		resp, err := suite.makeRequest(tt.method, fmt.Sprintf(tt.endpoint, sub.ID), tt.body)
+		assert.NoError(suite.T(), err)
+		resp.Body.Close()
+
+		assert.Equal(suite.T(), http.StatusOK, resp.StatusCode, tt.method+" "+tt.endpoint)
+		assert.Equal(suite.T(), "true", resp.Header.Get("Deprecation"), tt.method+" "+tt.endpoint)
+		assert.Equal(suite.T(), fmt.Sprintf(tt.link, sub.ID), resp.Header.Get("Link"), tt.method+" "+tt.endpoint)
+	}
+}
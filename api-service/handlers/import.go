@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"emtest/api-service/importer"
+	"emtest/api-service/problem"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Import subscriptions from a CSV or XLSX file
+// @Description Загрузка подписок из CSV или XLSX (первый лист). Первая строка содержит названия колонок:
+// @Description service_name, price, user_id, start_date и необязательные currency, billing_period, end_date.
+// @Description Каждая строка проверяется так же, как при создании подписки. Если хотя бы одна строка некорректна, ничего не импортируется
+// @Tags subscriptions
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param format query string false "File format, by the file extension by default" Enums(csv, xlsx)
+// @Param dry_run query bool false "Only validate the file"
+// @Success 200 {object} importer.Report "Import report"
+// @Failure 400 {object} problem.Problem "Missing or unreadable file"
+// @Failure 422 {object} importer.Report "Invalid rows, nothing imported"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/subscriptions/import [post]
+func (h *Handler) ImportSubscriptions(c *fiber.Ctx) error {
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request body", "A multipart form with a 'file' field is required").Send(c)
+	}
+
+	format, err := importer.ParseFormat(c.Query("format"), header.Filename)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid file format", err.Error()).Send(c)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Internal server error", err.Error()).Send(c)
+	}
+	defer file.Close()
+
+	report, err := importer.Import(c.UserContext(), h.subscriptions, file, format, c.QueryBool("dry_run"))
+	if errors.Is(err, importer.ErrInvalidFile) {
+		return problem.New(http.StatusBadRequest, "Invalid file", err.Error()).Send(c)
+	}
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to import subscriptions", err.Error()).Send(c)
+	}
+
+	if len(report.Errors) > 0 {
+		return c.Status(http.StatusUnprocessableEntity).JSON(report)
+	}
+	return c.JSON(report)
+}
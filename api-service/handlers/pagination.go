@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"emtest/api-service/audit"
+	"emtest/api-service/db"
+	"emtest/api-service/subscription"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// @description Pagination metadata
+type Pagination struct {
+	Limit      int    `json:"limit" example:"50"`
+	Sort       string `json:"sort" example:"created_at"`
+	Order      string `json:"order" example:"asc"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// @description Page of subscriptions
+type SubscriptionsPage struct {
+	Items      []subscription.Subscription `json:"items"`
+	Pagination Pagination                  `json:"pagination"`
+}
+
+// @description Page of audit log entries
+type AuditPage struct {
+	Items      []audit.Entry `json:"items"`
+	Pagination Pagination    `json:"pagination"`
+}
+
+// pageRequest is a parsed limit/cursor/sort/order query.
+type pageRequest struct {
+	limit int
+	sort  string
+	order string
+	after *db.ListCursor
+}
+
+// pageCursor points at the last item of the previous page. It is handed to
+// clients as an opaque base64 string.
+type pageCursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func parsePageRequest(limit, cursor, sort, order string) (pageRequest, error) {
+	page := pageRequest{limit: defaultPageLimit, sort: "created_at", order: "asc"}
+
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxPageLimit {
+			return page, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+		page.limit = value
+	}
+
+	if sort != "" {
+		if !db.SortColumns[sort] {
+			return page, fmt.Errorf("sort must be one of created_at, price, start_date")
+		}
+		page.sort = sort
+	}
+
+	if order != "" {
+		order = strings.ToLower(order)
+		if order != "asc" && order != "desc" {
+			return page, fmt.Errorf("order must be asc or desc")
+		}
+		page.order = order
+	}
+
+	if cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		if decoded.Sort != page.sort || decoded.Order != page.order {
+			return page, fmt.Errorf("cursor was issued for sort=%s&order=%s", decoded.Sort, decoded.Order)
+		}
+		value, err := decoded.value()
+		if err != nil {
+			return page, fmt.Errorf("invalid cursor")
+		}
+		page.after = &db.ListCursor{Value: value, ID: decoded.ID}
+	}
+
+	return page, nil
+}
+
+// options returns the repository list options of the page. They ask for one
+// item more than the limit to find out whether there is a next page.
+func (p pageRequest) options() db.ListOptions {
+	return db.ListOptions{Limit: p.limit + 1, Sort: p.sort, Order: p.order, After: p.after}
+}
+
+// page trims the extra item fetched by apply and builds pagination metadata.
+func (p pageRequest) page(subs []subscription.Subscription) SubscriptionsPage {
+	result := SubscriptionsPage{
+		Items:      subs,
+		Pagination: Pagination{Limit: p.limit, Sort: p.sort, Order: p.order},
+	}
+
+	if len(subs) > p.limit {
+		result.Items = subs[:p.limit]
+		result.Pagination.HasMore = true
+		result.Pagination.NextCursor = p.nextCursor(result.Items[p.limit-1])
+	}
+
+	return result
+}
+
+func (p pageRequest) nextCursor(last subscription.Subscription) string {
+	cursor := pageCursor{Sort: p.sort, Order: p.order, ID: last.ID}
+
+	switch p.sort {
+	case "price":
+		cursor.Value = strconv.Itoa(last.Price)
+	case "start_date":
+		cursor.Value = last.StartDate.String()
+	default:
+		cursor.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return cursor.encode()
+}
+
+// cursorAfter returns the repository cursor of the items following last.
+func (p pageRequest) cursorAfter(last subscription.Subscription) *db.ListCursor {
+	cursor := &db.ListCursor{Value: last.CreatedAt, ID: last.ID}
+
+	switch p.sort {
+	case "price":
+		cursor.Value = last.Price
+	case "start_date":
+		cursor.Value = last.StartDate
+	}
+
+	return cursor
+}
+
+// auditPage pages audit entries, which are always listed by creation time.
+func (p pageRequest) auditPage(entries []audit.Entry) AuditPage {
+	result := AuditPage{
+		Items:      entries,
+		Pagination: Pagination{Limit: p.limit, Sort: p.sort, Order: p.order},
+	}
+
+	if len(entries) > p.limit {
+		result.Items = entries[:p.limit]
+		result.Pagination.HasMore = true
+
+		last := result.Items[p.limit-1]
+		cursor := pageCursor{Sort: p.sort, Order: p.order, Value: last.CreatedAt.UTC().Format(time.RFC3339Nano), ID: last.ID}
+		result.Pagination.NextCursor = cursor.encode()
+	}
+
+	return result
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// value parses the sort column value the cursor points at.
+func (c pageCursor) value() (interface{}, error) {
+	switch c.Sort {
+	case "price":
+		return strconv.Atoi(c.Value)
+	case "start_date":
+		return subscription.ParseMonth(c.Value)
+	default:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	}
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+	var decoded pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return decoded, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || !db.SortColumns[decoded.Sort] {
+		return decoded, fmt.Errorf("invalid cursor")
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"emtest/api-service/subscription"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageRequest_CursorRoundTrip(t *testing.T) {
+	page, err := parsePageRequest("2", "", "price", "desc")
+	assert.NoError(t, err)
+
+	subs := []subscription.Subscription{
+		{ID: uuid.New(), Price: 300},
+		{ID: uuid.New(), Price: 200},
+		{ID: uuid.New(), Price: 100},
+	}
+
+	result := page.page(subs)
+	assert.Len(t, result.Items, 2)
+	assert.True(t, result.Pagination.HasMore)
+
+	next, err := parsePageRequest("2", result.Pagination.NextCursor, "price", "desc")
+	assert.NoError(t, err)
+	assert.Equal(t, subs[1].ID, next.after.ID)
+	assert.Equal(t, 200, next.after.Value)
+	assert.Equal(t, 3, next.options().Limit)
+
+	_, err = parsePageRequest("2", result.Pagination.NextCursor, "price", "asc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"emtest/api-service/currency"
+	"emtest/api-service/db"
+	"emtest/api-service/problem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary List exchange rates
+// @Description Курсы валют, используемые для пересчета стоимости подписок. Курс задается в базовой валюте (RUB) за единицу валюты
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {array} currency.Rate "Exchange rates"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin token"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/admin/rates [get]
+func (h *Handler) GetRates(c *fiber.Ctx) error {
+
+	rates, err := h.rates.ListRates(c.UserContext())
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to fetch exchange rates", err.Error()).Send(c)
+	}
+
+	return c.JSON(rates)
+}
+
+// @Summary Set exchange rate
+// @Description Создание или обновление курса валюты
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param body body currency.Rate true "Exchange rate"
+// @Success 200 {object} currency.Rate "Saved exchange rate"
+// @Failure 400 {object} problem.Problem "Invalid exchange rate"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin token"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/admin/rates [put]
+func (h *Handler) SetRate(c *fiber.Ctx) error {
+
+	var rate currency.Rate
+
+	if err := parseBody(c, &rate); err != nil {
+		return problem.Body(err).Send(c)
+	}
+
+	rate.Currency = strings.ToUpper(rate.Currency)
+
+	if err := validate.Struct(rate); err != nil {
+		return problem.Validation(err).Send(c)
+	}
+
+	if rate.Currency == currency.Base {
+		return problem.New(http.StatusBadRequest, "Validation failed", fmt.Sprintf("Rate of the base currency %s is always 1", currency.Base)).Send(c)
+	}
+
+	if err := h.rates.SetRate(c.UserContext(), &rate); err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to save exchange rate", err.Error()).Send(c)
+	}
+
+	return c.JSON(rate)
+}
+
+// @Summary Delete exchange rate
+// @Description Удаление курса валюты
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Param currency query string true "Currency code (ISO 4217)" Example(USD)
+// @Success 200 {object} SuccessResponse "Success message"
+// @Failure 400 {object} problem.Problem "Bad request - missing currency"
+// @Failure 401 {object} problem.Problem "Missing or invalid admin token"
+// @Failure 404 {object} problem.Problem "Exchange rate not found"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/admin/rates [delete]
+func (h *Handler) DeleteRate(c *fiber.Ctx) error {
+
+	code := strings.ToUpper(c.Query("currency"))
+	if code == "" {
+		return problem.New(http.StatusBadRequest, "Bad request", "Parameter 'currency' is required").Send(c)
+	}
+
+	err := h.rates.DeleteRate(c.UserContext(), code)
+	if errors.Is(err, db.ErrNotFound) {
+		return problem.New(http.StatusNotFound, "Exchange rate not found", fmt.Sprintf("Exchange rate for %s not found", code)).Send(c)
+	}
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Internal server error", err.Error()).Send(c)
+	}
+
+	return c.JSON(SuccessResponse{Message: "Exchange rate deleted successfully"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"emtest/api-service/config"
+	"emtest/api-service/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterRoutes mounts the /api/v1 routes of h on app. The admin routes
+// require cfg.Admin.Token.
+func RegisterRoutes(app fiber.Router, h *Handler, cfg config.Config) {
+	v1 := app.Group("/api/v1")
+
+	v1.Post("/subscriptions", h.CreateSubscription)
+	v1.Post("/subscriptions/bulk", h.BulkSubscriptions)
+	v1.Post("/subscriptions/import", h.ImportSubscriptions)
+	v1.Get("/subscriptions/export", h.ExportSubscriptions)
+
+	v1.Get("/subscriptions/calculate", h.CalculateTotalCost)
+	v1.Get("/subscriptions/calculate/breakdown", h.CalculateCostBreakdown)
+	v1.Get("/subscriptions/calculate/breakdown/export", h.ExportCostBreakdown)
+	v1.Get("/subscriptions/calculate/monthly", h.CalculateMonthlyCost)
+	v1.Get("/users/:user_id/statement", h.GetUserStatement)
+
+	h.RegisterV1Compat(v1)
+
+	v1.Get("/subscriptions/:id", h.GetSubscription)
+	v1.Put("/subscriptions/:id", h.ReplaceSubscription)
+	v1.Patch("/subscriptions/:id", h.UpdateSubscription)
+	v1.Delete("/subscriptions/:id", h.DeleteSubscription)
+	v1.Get("/subscriptions/:id/prices", h.GetPriceHistory)
+	v1.Post("/subscriptions/:id/restore", h.RestoreSubscription)
+	v1.Get("/subscriptions/:id/history", h.GetSubscriptionHistory)
+
+	admin := v1.Group("/admin", middleware.AdminAuth(cfg.Admin.Token))
+
+	admin.Get("/rates", h.GetRates)
+	admin.Put("/rates", h.SetRate)
+	admin.Delete("/rates", h.DeleteRate)
+
+	admin.Get("/audit", h.GetAuditLog)
+}
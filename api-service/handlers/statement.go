@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"emtest/api-service/problem"
+	"emtest/api-service/subscription"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+//go:embed templates/statement.html
+var templateFiles embed.FS
+
+var statementTemplate = template.Must(template.New("statement.html").Funcs(template.FuncMap{
+	"amount": func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	},
+}).ParseFS(templateFiles, "templates/statement.html"))
+
+// statement is the data of the printable statement of a user.
+type statement struct {
+	UserId   uuid.UUID
+	From     subscription.Month
+	To       subscription.Month
+	Mode     subscription.AccountingMode
+	Currency string
+	IssuedAt time.Time
+	Lines    []statementLine
+	Total    float64
+}
+
+// statementLine is one subscription of a statement, its cost converted into
+// the statement currency.
+type statementLine struct {
+	ServiceName   string
+	BillingPeriod subscription.BillingPeriod
+	Price         int
+	Currency      string
+	StartDate     subscription.Month
+	EndDate       string
+	Months        int
+	Cost          float64
+}
+
+// @Summary Get user statement
+// @Description Печатная HTML-выписка по подпискам пользователя за период: каждая подписка со стоимостью в target_currency и итог.
+// @Description Страница рассчитана на печать и сохранение в PDF из браузера
+// @Tags subscriptions
+// @Produce html
+// @Param user_id path string true "User ID (UUID format)" Format(uuid)
+// @Param service_name query string false "Filter by service name"
+// @Param include_deleted query bool false "Admin only: include deleted subscriptions"
+// @Param start_date query string false "Period start (MM-YYYY format)" Format(MM-YYYY)
+// @Param end_date query string false "Period end (MM-YYYY format)" Format(MM-YYYY)
+// @Param mode query string false "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged" Enums(accrual, cash) default(accrual)
+// @Param target_currency query string false "Currency of the statement (ISO 4217)" default(RUB)
+// @Success 200 {string} string "HTML statement"
+// @Failure 400 {object} problem.Problem "Malformed user ID, invalid period, accounting mode or currency"
+// @Failure 403 {object} problem.Problem "include_deleted without admin token"
+// @Failure 422 {object} problem.Problem "Missing exchange rate"
+// @Failure 500 {object} problem.Problem "Internal server error"
+// @Router /api/v1/users/{user_id}/statement [get]
+func (h *Handler) GetUserStatement(c *fiber.Ctx) error {
+
+	userId, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Bad request", "Invalid user ID format").Send(c)
+	}
+
+	req, costErr := h.newCostRequest(c)
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+	req.filter.UserId = &userId
+
+	data := statement{
+		UserId:   userId,
+		From:     req.from,
+		To:       req.to,
+		Mode:     req.mode,
+		Currency: req.currency,
+		IssuedAt: time.Now().UTC(),
+		Lines:    []statementLine{},
+	}
+
+	costErr = req.aggregate(c, func(sub subscription.Subscription, factor float64) {
+		line := statementLine{
+			ServiceName:   sub.ServiceName,
+			BillingPeriod: sub.BillingPeriod,
+			Price:         sub.Price,
+			Currency:      sub.Currency,
+			StartDate:     sub.StartDate,
+			Months:        sub.ActiveMonths(req.from, req.to),
+			Cost:          sub.Cost(req.from, req.to, req.mode) * factor,
+		}
+		if sub.EndDate != nil {
+			line.EndDate = sub.EndDate.String()
+		}
+
+		data.Lines = append(data.Lines, line)
+		data.Total += line.Cost
+	})
+	if costErr != nil {
+		return costErr.Send(c)
+	}
+
+	sort.SliceStable(data.Lines, func(i, j int) bool {
+		left, right := data.Lines[i], data.Lines[j]
+		if left.ServiceName != right.ServiceName {
+			return left.ServiceName < right.ServiceName
+		}
+		return left.StartDate.Before(right.StartDate)
+	})
+
+	data.Total = subscription.RoundAmount(data.Total)
+	for i := range data.Lines {
+		data.Lines[i].Cost = subscription.RoundAmount(data.Lines[i].Cost)
+	}
+
+	var body bytes.Buffer
+	if err := statementTemplate.Execute(&body, data); err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to render statement", err.Error()).Send(c)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(body.Bytes())
+}
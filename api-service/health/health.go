@@ -0,0 +1,136 @@
+// Package health serves the liveness and readiness probes of the service.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+// @description State of the service and, for readiness, of each of its
+// @description dependencies.
+type Response struct {
+	Status string                 `json:"status" enums:"up,down" example:"up"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// @description State of a dependency along with how long checking it took.
+type CheckResult struct {
+	Status     string  `json:"status" enums:"up,down" example:"up"`
+	DurationMs float64 `json:"duration_ms" example:"1.2"`
+	Error      string  `json:"error,omitempty" example:"context deadline exceeded"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health runs the checks of the dependencies of the service.
+type Health struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// New returns probes giving each check up to timeout, 2s when zero.
+func New(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Health{timeout: timeout}
+}
+
+// Add registers the check of a dependency under name.
+func (h *Health) Add(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// @Summary Liveness probe
+// @Description Процесс запущен и отвечает на запросы. Зависимости не проверяются
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Response
+// @Router /healthz [get]
+func (h *Health) Liveness(c *fiber.Ctx) error {
+	return c.JSON(Response{Status: StatusUp})
+}
+
+// @Summary Readiness probe
+// @Description Готовность принимать запросы: проверка каждой зависимости (доступность базы данных, версия схемы) с таймаутом.
+// @Description Если хотя бы одна зависимость недоступна, возвращается 503
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Response "Every dependency is up"
+// @Failure 503 {object} health.Response "Some dependency is down"
+// @Router /readyz [get]
+func (h *Health) Readiness(c *fiber.Ctx) error {
+	response := h.run(c.UserContext())
+	if response.Status != StatusUp {
+		return c.Status(http.StatusServiceUnavailable).JSON(response)
+	}
+	return c.JSON(response)
+}
+
+// run checks the dependencies concurrently.
+func (h *Health) run(ctx context.Context) Response {
+	response := Response{Status: StatusUp, Checks: make(map[string]CheckResult, len(h.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+			result := h.runCheck(ctx, check.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.name] = result
+			if result.Status != StatusUp {
+				response.Status = StatusDown
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return response
+}
+
+// runCheck runs check within the timeout. A check ignoring its context is
+// reported as down once the timeout passes.
+func (h *Health) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusUp, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, h *Health, path string) (int, Response) {
+	app := fiber.New()
+	app.Get("/healthz", h.Liveness)
+	app.Get("/readyz", h.Readiness)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var response Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return resp.StatusCode, response
+}
+
+func TestLiveness(t *testing.T) {
+	h := New(0)
+	h.Add("database", func(context.Context) error { return errors.New("connection refused") })
+
+	status, response := probe(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, StatusUp, response.Status)
+	assert.Empty(t, response.Checks)
+}
+
+func TestReadiness(t *testing.T) {
+	h := New(50 * time.Millisecond)
+	h.Add("database", func(context.Context) error { return nil })
+
+	status, response := probe(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, StatusUp, response.Status)
+	assert.Equal(t, StatusUp, response.Checks["database"].Status)
+
+	h.Add("migrations", func(context.Context) error { return errors.New("migration 0008_allow_overlap is not applied") })
+	h.Add("slow", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	status, response = probe(t, h, "/readyz")
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusDown, response.Status)
+	assert.Equal(t, StatusUp, response.Checks["database"].Status)
+	assert.Equal(t, CheckResult{Status: StatusDown, DurationMs: response.Checks["migrations"].DurationMs, Error: "migration 0008_allow_overlap is not applied"}, response.Checks["migrations"])
+	assert.Equal(t, StatusDown, response.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), response.Checks["slow"].Error)
+}
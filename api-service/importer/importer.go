@@ -0,0 +1,291 @@
+package importer
+
+import (
+	"context"
+	"emtest/api-service/db"
+	"emtest/api-service/subscription"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrInvalidFile is returned when the file as a whole cannot be imported.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// Format is the file format of an import.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// MaxRows caps the number of subscriptions one file may hold.
+const MaxRows = 10000
+
+// Columns lists the columns an import file may have, named after the JSON
+// fields of subscription.Subscription.
+var Columns = []string{"service_name", "price", "currency", "billing_period", "user_id", "start_date", "end_date", "allow_overlap"}
+
+var requiredColumns = []string{"service_name", "price", "user_id", "start_date"}
+
+// RowError is a problem with one row of the file. Rows are numbered the way
+// spreadsheets do, the header being row 1.
+type RowError struct {
+	Row     int    `json:"row" example:"3"`
+	Column  string `json:"column,omitempty" example:"price"`
+	Message string `json:"message" example:"must be greater than 0"`
+}
+
+// @description Import report object
+type Report struct {
+	DryRun   bool       `json:"dry_run"`
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Errors   []RowError `json:"errors"`
+}
+
+// ParseFormat returns the format named by format, or by the extension of
+// filename when format is empty.
+func ParseFormat(format, filename string) (Format, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+
+	switch Format(strings.ToLower(format)) {
+	case CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	}
+	return "", fmt.Errorf("unsupported file format %q: expected csv or xlsx", format)
+}
+
+// errRollback discards the transaction of a dry run or of a file with rows
+// failing on the database.
+var errRollback = errors.New("import rolled back")
+
+// Import reads subscriptions from r and validates every row the way
+// CreateSubscription does. When no row is invalid, the subscriptions are
+// created in one transaction, which also reports rows overlapping other
+// subscriptions. The transaction is committed unless dryRun is set or some row
+// failed, so that either all subscriptions are created or none. An unreadable
+// file, a bad header or too many rows fail with ErrInvalidFile.
+func Import(ctx context.Context, subscriptions db.SubscriptionRepository, r io.Reader, format Format, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun, Errors: []RowError{}}
+
+	records, err := readRecords(r, format)
+	if err != nil {
+		return report, err
+	}
+	if len(records) == 0 {
+		return report, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+
+	header, err := parseHeader(records[0])
+	if err != nil {
+		return report, err
+	}
+
+	var subs []subscription.Subscription
+	var rows []int
+	for i, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		report.Rows++
+		if report.Rows > MaxRows {
+			return report, fmt.Errorf("%w: the file has more than %d rows", ErrInvalidFile, MaxRows)
+		}
+
+		sub, rowErrors := parseRow(header, record, i+2)
+		report.Errors = append(report.Errors, rowErrors...)
+		if len(rowErrors) == 0 {
+			subs = append(subs, sub)
+			rows = append(rows, i+2)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	err = subscriptions.Transaction(ctx, func(tx db.SubscriptionRepository) error {
+		for i := range subs {
+			err := tx.Create(ctx, &subs[i])
+			if errors.Is(err, db.ErrOverlap) {
+				report.Errors = append(report.Errors, RowError{Row: rows[i], Message: err.Error()})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if dryRun || len(report.Errors) > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Imported = len(subs)
+	return report, nil
+}
+
+// readRecords returns the rows of a CSV file or of the first sheet of an XLSX
+// workbook.
+func readRecords(r io.Reader, format Format) ([][]string, error) {
+	switch format {
+	case CSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read CSV: %s", ErrInvalidFile, err)
+		}
+		return records, nil
+
+	case XLSX:
+		workbook, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read XLSX: %s", ErrInvalidFile, err)
+		}
+		defer workbook.Close()
+
+		sheets := workbook.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, nil
+		}
+		records, err := workbook.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read XLSX: %s", ErrInvalidFile, err)
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported file format %q", ErrInvalidFile, format)
+}
+
+// parseHeader returns the column of every cell of the header row.
+func parseHeader(record []string) ([]string, error) {
+	known := make(map[string]bool, len(Columns))
+	for _, column := range Columns {
+		known[column] = true
+	}
+
+	header := make([]string, len(record))
+	seen := make(map[string]bool, len(record))
+	for i, cell := range record {
+		// Spreadsheet tools often start CSV files with a byte order mark.
+		column := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff")))
+		if !known[column] {
+			return nil, fmt.Errorf("%w: unknown column %q, expected %s", ErrInvalidFile, cell, strings.Join(Columns, ", "))
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidFile, column)
+		}
+		header[i], seen[column] = column, true
+	}
+
+	for _, column := range requiredColumns {
+		if !seen[column] {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, column)
+		}
+	}
+	return header, nil
+}
+
+// parseRow builds the subscription of the record at row and validates it.
+func parseRow(header, record []string, row int) (subscription.Subscription, []RowError) {
+	var sub subscription.Subscription
+	var rowErrors []RowError
+
+	fail := func(column, message string) {
+		rowErrors = append(rowErrors, RowError{Row: row, Column: column, Message: message})
+	}
+
+	for i, column := range header {
+		value := ""
+		if i < len(record) {
+			value = strings.TrimSpace(record[i])
+		}
+		if value == "" {
+			continue
+		}
+
+		switch column {
+		case "service_name":
+			sub.ServiceName = value
+		case "price":
+			price, err := strconv.Atoi(value)
+			if err != nil {
+				fail(column, fmt.Sprintf("expected a whole number, got %q", value))
+			}
+			sub.Price = price
+		case "currency":
+			sub.Currency = value
+		case "billing_period":
+			sub.BillingPeriod = subscription.BillingPeriod(strings.ToLower(value))
+		case "user_id":
+			userId, err := uuid.Parse(value)
+			if err != nil {
+				fail(column, fmt.Sprintf("expected a UUID, got %q", value))
+			}
+			sub.UserId = userId
+		case "allow_overlap":
+			allow, err := strconv.ParseBool(value)
+			if err != nil {
+				fail(column, fmt.Sprintf("expected true or false, got %q", value))
+			}
+			sub.AllowOverlap = allow
+		case "start_date", "end_date":
+			month, err := subscription.ParseMonth(value)
+			if err != nil {
+				fail(column, err.Error())
+				continue
+			}
+			if column == "start_date" {
+				sub.StartDate = month
+			} else {
+				sub.EndDate = &month
+			}
+		}
+	}
+	if len(rowErrors) > 0 {
+		return sub, rowErrors
+	}
+
+	if err := sub.Prepare(); err != nil {
+		var validationErrors subscription.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			fail("", err.Error())
+		}
+		for _, err := range validationErrors {
+			fail(err.Field, err.Message)
+		}
+	}
+	return sub, rowErrors
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
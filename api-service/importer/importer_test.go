@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"emtest/api-service/db"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+const userId = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func listAll(t *testing.T, store db.Store) int {
+	subs, err := store.List(context.Background(), db.SubscriptionFilter{}, db.ListOptions{Limit: 100, Sort: "created_at", Order: "asc"})
+	assert.NoError(t, err)
+	return len(subs)
+}
+
+func TestImport_CSV(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryRepository()
+
+	file := "\ufeffService_Name,price,currency,billing_period,user_id,start_date,end_date\n" +
+		"Netflix,400,usd,Yearly," + userId + ",01-2025,\n" +
+		"\n" +
+		"Spotify,199,,," + userId + ",02-2025,12-2025\n"
+
+	report, err := Import(ctx, store, strings.NewReader(file), CSV, true)
+	assert.NoError(t, err)
+	assert.Equal(t, Report{DryRun: true, Rows: 2, Errors: []RowError{}}, report)
+	assert.Zero(t, listAll(t, store))
+
+	report, err = Import(ctx, store, strings.NewReader(file), CSV, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, listAll(t, store))
+
+	subs, err := store.List(ctx, db.SubscriptionFilter{ServiceName: "Netflix"}, db.ListOptions{Limit: 1, Sort: "created_at", Order: "asc"})
+	assert.NoError(t, err)
+	if assert.Len(t, subs, 1) {
+		assert.Equal(t, "USD", subs[0].Currency)
+		assert.Equal(t, "yearly", string(subs[0].BillingPeriod))
+		assert.Nil(t, subs[0].EndDate)
+	}
+}
+
+func TestImport_RowErrors(t *testing.T) {
+	store := db.NewMemoryRepository()
+
+	file := "service_name,price,user_id,start_date,end_date\n" +
+		"Netflix,400," + userId + ",01-2025,\n" +
+		",-1," + userId + ",01-2025,\n" +
+		"Spotify,cheap,someone,13-2025,2025\n"
+
+	report, err := Import(context.Background(), store, strings.NewReader(file), CSV, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Rows)
+	assert.Zero(t, report.Imported)
+	assert.Equal(t, []RowError{
+		{Row: 3, Column: "service_name", Message: "is required"},
+		{Row: 3, Column: "price", Message: "must be greater than 0"},
+		{Row: 4, Column: "price", Message: `expected a whole number, got "cheap"`},
+		{Row: 4, Column: "user_id", Message: `expected a UUID, got "someone"`},
+		{Row: 4, Column: "start_date", Message: "invalid month: must be between 1 and 12"},
+		{Row: 4, Column: "end_date", Message: "invalid date format: expected 'MM-YYYY'"},
+	}, report.Errors)
+	assert.Zero(t, listAll(t, store))
+}
+
+func TestImport_Overlap(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryRepository()
+
+	file := "service_name,price,user_id,start_date,end_date,allow_overlap\n" +
+		"Netflix,400," + userId + ",01-2025,06-2025,\n" +
+		"Netflix,500," + userId + ",06-2025,,\n" +
+		"Netflix,600," + userId + ",03-2025,,true\n" +
+		"Spotify,199," + userId + ",01-2025,,\n" +
+		"Netflix,400," + userId + ",01-2024,01-2025,\n"
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := Import(ctx, store, strings.NewReader(file), CSV, dryRun)
+		assert.NoError(t, err)
+		assert.Zero(t, report.Imported)
+		if assert.Len(t, report.Errors, 2) {
+			assert.Equal(t, 3, report.Errors[0].Row)
+			assert.Contains(t, report.Errors[0].Message, "overlapping subscription")
+			assert.Equal(t, 6, report.Errors[1].Row)
+		}
+		assert.Zero(t, listAll(t, store))
+	}
+}
+
+func TestImport_InvalidFile(t *testing.T) {
+	for name, file := range map[string]string{
+		"empty":          "",
+		"unknown column": "service_name,price,user_id,start_date,owner\n",
+		"missing column": "service_name,price,user_id\n",
+		"twice":          "service_name,price,user_id,start_date,price\n",
+		"broken quotes":  "service_name,\"price\n",
+	} {
+		_, err := Import(context.Background(), db.NewMemoryRepository(), strings.NewReader(file), CSV, false)
+		assert.ErrorIs(t, err, ErrInvalidFile, name)
+	}
+}
+
+func TestImport_XLSX(t *testing.T) {
+	workbook := excelize.NewFile()
+	rows := [][]interface{}{
+		{"service_name", "price", "user_id", "start_date"},
+		{"Netflix", 400, uuid.NewString(), "01-2025"},
+		{"Spotify", 199, uuid.NewString(), "03-2025"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		assert.NoError(t, workbook.SetSheetRow("Sheet1", cell, &row))
+	}
+
+	var file bytes.Buffer
+	assert.NoError(t, workbook.Write(&file))
+
+	store := db.NewMemoryRepository()
+	report, err := Import(context.Background(), store, &file, XLSX, false)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, listAll(t, store))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("", "subscriptions.CSV")
+	assert.NoError(t, err)
+	assert.Equal(t, CSV, format)
+
+	format, err = ParseFormat("xlsx", "upload")
+	assert.NoError(t, err)
+	assert.Equal(t, XLSX, format)
+
+	_, err = ParseFormat("", "subscriptions.ods")
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"emtest/api-service/gormhooks"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+// gormPlugin times every query GORM runs.
+type gormPlugin struct {
+	duration *prometheus.HistogramVec
+}
+
+// GormPlugin returns the plugin recording db_query_duration_seconds, to be
+// installed with gorm.DB.Use.
+func (m *Metrics) GormPlugin() gorm.Plugin {
+	return gormPlugin{duration: m.queryDuration}
+}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	return gormhooks.Register(db, "metrics", func(operation string) (before, after func(*gorm.DB)) {
+		return startQuery, p.observe(operation)
+	})
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p gormPlugin) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		p.duration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
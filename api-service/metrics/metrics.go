@@ -0,0 +1,105 @@
+// Package metrics exposes Prometheus metrics of the service: HTTP requests,
+// database queries and the subscriptions it stores.
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the collectors of the service in a registry of its own.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	requests        *prometheus.CounterVec
+	queryDuration   *prometheus.HistogramVec
+}
+
+// New returns the HTTP and database metrics along with the Go runtime and
+// process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duration of database queries by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.requests,
+		m.queryDuration,
+	)
+	return m
+}
+
+// Register adds collectors, such as the one of NewSubscriptionCollector, to
+// the metrics.
+func (m *Metrics) Register(collectors ...prometheus.Collector) error {
+	for _, collector := range collectors {
+		if err := m.registry.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics in the Prometheus text format. A collector that
+// fails leaves its metrics out instead of failing the whole scrape.
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}))
+}
+
+// Middleware records the duration and status of requests. Requests are
+// labelled by their route pattern rather than path, so that /subscriptions/:id
+// is a single series.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		duration := time.Since(start)
+
+		// The error handler sets the status of failed requests only after the
+		// middleware returns.
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Method(),
+			"route":  c.Route().Path,
+			"status": strconv.Itoa(status),
+		}
+		m.requestDuration.With(labels).Observe(duration.Seconds())
+		m.requests.With(labels).Inc()
+
+		return err
+	}
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"emtest/api-service/config"
+	"emtest/api-service/currency"
+	"emtest/api-service/db"
+	"emtest/api-service/subscription"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+
+	app := fiber.New()
+	app.Use(m.Middleware())
+	app.Get("/subscriptions/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "missing" {
+			return fiber.ErrNotFound
+		}
+		return c.SendString("ok")
+	})
+	app.Get("/metrics", m.Handler())
+
+	for _, path := range []string{"/subscriptions/1", "/subscriptions/2", "/subscriptions/missing"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/subscriptions/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/subscriptions/:id", "404")))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `http_request_duration_seconds_count{method="GET",route="/subscriptions/:id",status="200"} 2`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestGormPlugin(t *testing.T) {
+	m := New()
+
+	database := config.Database{Driver: db.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")}
+	conn, err := db.Open(database)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := conn.DB()
+		sqlDB.Close()
+	})
+	require.NoError(t, conn.Use(m.GormPlugin()))
+
+	require.NoError(t, conn.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)").Error)
+	var count int64
+	require.NoError(t, conn.Table("items").Count(&count).Error)
+
+	families, err := m.registry.Gather()
+	require.NoError(t, err)
+
+	queries := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, 2)
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			queries[strings.Join(labels, " ")] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, map[string]uint64{"raw ": 1, "query items": 1}, queries)
+}
+
+func TestSubscriptionCollector(t *testing.T) {
+	store := db.NewMemoryRepository()
+	ctx := context.Background()
+
+	require.NoError(t, store.SetRate(ctx, &currency.Rate{Currency: "USD", Rate: 90}))
+
+	month := subscription.CurrentMonth()
+	ended := month.AddMonths(-1)
+	for _, sub := range []subscription.Subscription{
+		{ServiceName: "Netflix", Price: 600, Currency: "RUB", BillingPeriod: subscription.Monthly, UserId: uuid.New(), StartDate: month},
+		{ServiceName: "Netflix", Price: 10, Currency: "USD", BillingPeriod: subscription.Monthly, UserId: uuid.New(), StartDate: month},
+		{ServiceName: "Spotify", Price: 1200, Currency: "RUB", BillingPeriod: subscription.Yearly, UserId: uuid.New(), StartDate: month},
+		{ServiceName: "Spotify", Price: 300, Currency: "RUB", BillingPeriod: subscription.Monthly, UserId: uuid.New(), StartDate: ended.AddMonths(-1), EndDate: &ended},
+	} {
+		require.NoError(t, store.Create(ctx, &sub))
+	}
+
+	expected := `
+# HELP subscriptions_active Number of subscriptions active in the current month.
+# TYPE subscriptions_active gauge
+subscriptions_active 3
+# HELP subscriptions_active_by_service Number of subscriptions active in the current month by service.
+# TYPE subscriptions_active_by_service gauge
+subscriptions_active_by_service{service_name="Netflix"} 2
+subscriptions_active_by_service{service_name="Spotify"} 1
+# HELP subscriptions_monthly_recurring_cost Accrued cost of the subscriptions active in the current month, in the base currency.
+# TYPE subscriptions_monthly_recurring_cost gauge
+subscriptions_monthly_recurring_cost{currency="RUB"} 1600
+`
+	collector := NewSubscriptionCollector(store, store)
+	collector.refresh(ctx)
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	// The cost is served from the last refresh while the counts are live.
+	require.NoError(t, store.Create(ctx, &subscription.Subscription{ServiceName: "Spotify", Price: 500, Currency: "RUB", BillingPeriod: subscription.Monthly, UserId: uuid.New(), StartDate: month}))
+	expected = strings.Replace(expected, "subscriptions_active 3", "subscriptions_active 4", 1)
+	expected = strings.Replace(expected, `service_name="Spotify"} 1`, `service_name="Spotify"} 2`, 1)
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	collector.refresh(ctx)
+	expected = strings.Replace(expected, `{currency="RUB"} 1600`, `{currency="RUB"} 2100`, 1)
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"emtest/api-service/currency"
+	"emtest/api-service/db"
+	"emtest/api-service/subscription"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// collectTimeout bounds the queries of one scrape of the subscription
+	// counts, and of one refresh of the recurring cost.
+	collectTimeout = 10 * time.Second
+	// defaultCostRefreshInterval is used when metrics.cost_refresh_interval
+	// is not set.
+	defaultCostRefreshInterval = time.Minute
+)
+
+var (
+	activeDesc = prometheus.NewDesc(
+		"subscriptions_active",
+		"Number of subscriptions active in the current month.",
+		nil, nil,
+	)
+	activeByServiceDesc = prometheus.NewDesc(
+		"subscriptions_active_by_service",
+		"Number of subscriptions active in the current month by service.",
+		[]string{"service_name"}, nil,
+	)
+	recurringCostDesc = prometheus.NewDesc(
+		"subscriptions_monthly_recurring_cost",
+		"Accrued cost of the subscriptions active in the current month, in the base currency.",
+		[]string{"currency"}, nil,
+	)
+)
+
+// SubscriptionCollector counts the active subscriptions on every scrape, so
+// that the counts never lag behind changes made by other instances. The
+// recurring cost needs the price history of every active subscription: it is
+// computed by Run in the background and served from the last refresh.
+type SubscriptionCollector struct {
+	subscriptions db.SubscriptionRepository
+	rates         db.RateRepository
+
+	mu        sync.RWMutex
+	refreshed bool
+	cost      float64
+	costErr   error
+}
+
+// NewSubscriptionCollector returns the collector of the active subscription
+// counts and of the monthly recurring cost.
+func NewSubscriptionCollector(subscriptions db.SubscriptionRepository, rates db.RateRepository) *SubscriptionCollector {
+	return &SubscriptionCollector{subscriptions: subscriptions, rates: rates}
+}
+
+func (c *SubscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeDesc
+	ch <- activeByServiceDesc
+	ch <- recurringCostDesc
+}
+
+func (c *SubscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	month := subscription.CurrentMonth()
+	byService, err := c.subscriptions.CountByService(ctx, db.SubscriptionFilter{ActiveFrom: month, ActiveTo: month})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(activeDesc, err)
+		ch <- prometheus.NewInvalidMetric(activeByServiceDesc, err)
+	} else {
+		active := int64(0)
+		for service, count := range byService {
+			active += count
+			ch <- prometheus.MustNewConstMetric(activeByServiceDesc, prometheus.GaugeValue, float64(count), service)
+		}
+		ch <- prometheus.MustNewConstMetric(activeDesc, prometheus.GaugeValue, float64(active))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case !c.refreshed:
+	case c.costErr != nil:
+		ch <- prometheus.NewInvalidMetric(recurringCostDesc, c.costErr)
+	default:
+		ch <- prometheus.MustNewConstMetric(recurringCostDesc, prometheus.GaugeValue, c.cost, currency.Base)
+	}
+}
+
+// Run refreshes the recurring cost once right away and then every interval,
+// one minute when zero, until ctx is done.
+func (c *SubscriptionCollector) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultCostRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh computes the recurring cost of the current month. A failed refresh
+// is reported by the next scrapes until one succeeds.
+func (c *SubscriptionCollector) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	cost, err := c.recurringCost(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to compute the monthly recurring cost: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshed, c.cost, c.costErr = true, cost, err
+}
+
+func (c *SubscriptionCollector) recurringCost(ctx context.Context) (float64, error) {
+	storedRates, err := c.rates.ListRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rates := currency.NewRates(storedRates)
+
+	month := subscription.CurrentMonth()
+	cost := 0.0
+	filter := db.SubscriptionFilter{ActiveFrom: month, ActiveTo: month}
+	err = c.subscriptions.AggregateCost(ctx, filter, func(sub subscription.Subscription) error {
+		amount, err := rates.Convert(sub.AmountIn(month, subscription.Accrual), sub.Currency, currency.Base)
+		if err != nil {
+			return err
+		}
+		cost += amount
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return subscription.RoundAmount(cost), nil
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"emtest/api-service/audit"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// ActorHeader names who makes the request. It is set by the gateway in front
+// of the service, which authenticates users.
+const ActorHeader = "X-Actor"
+
+// GatewaySecretHeader carries the shared secret proving that a request comes
+// from the gateway, so that its X-Actor header can be trusted.
+const GatewaySecretHeader = "X-Gateway-Secret"
+
+const (
+	adminActor     = "admin"
+	anonymousActor = "anonymous"
+)
+
+// Actor records subscription changes made by the request in the audit log as
+// made by the X-Actor header value, by "admin" for requests carrying the admin
+// token and by "anonymous" otherwise. X-Actor is only trusted on requests
+// carrying the admin token or the gateway secret: anyone could send it.
+func Actor(adminToken, gatewaySecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		isAdmin := IsAdmin(c, adminToken)
+
+		actor := anonymousActor
+		if isAdmin {
+			actor = adminActor
+		}
+		if isAdmin || isGateway(c, gatewaySecret) {
+			// The header value points into a buffer Fiber reuses for the next
+			// request, while the actor outlives it in the audit log.
+			if named := utils.CopyString(c.Get(ActorHeader)); named != "" {
+				actor = named
+			}
+		}
+
+		c.SetUserContext(audit.WithActor(c.UserContext(), actor))
+		return c.Next()
+	}
+}
+
+// isGateway reports whether the request carries the configured gateway
+// secret. An empty secret trusts no gateway.
+func isGateway(c *fiber.Ctx, secret string) bool {
+	provided := c.Get(GatewaySecretHeader)
+	return secret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) == 1
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"emtest/api-service/audit"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActor(t *testing.T) {
+	app := fiber.New()
+	app.Use(Actor("admin-token", "gateway-secret"))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(audit.ActorFrom(c.UserContext()))
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"Anonymous", nil, anonymousActor},
+		{"Untrusted actor", map[string]string{ActorHeader: "ceo@example.com"}, anonymousActor},
+		{"Wrong gateway secret", map[string]string{ActorHeader: "ceo@example.com", GatewaySecretHeader: "guess"}, anonymousActor},
+		{"Gateway", map[string]string{ActorHeader: "support@example.com", GatewaySecretHeader: "gateway-secret"}, "support@example.com"},
+		{"Admin", map[string]string{AdminTokenHeader: "admin-token"}, adminActor},
+		{"Named admin", map[string]string{ActorHeader: "ops@example.com", AdminTokenHeader: "admin-token"}, "ops@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for header, value := range tt.headers {
+				req.Header.Set(header, value)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+
+	// Without a configured secret no gateway is trusted.
+	app = fiber.New()
+	app.Use(Actor("", ""))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(audit.ActorFrom(c.UserContext()))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(ActorHeader, "ceo@example.com")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, anonymousActor, string(body))
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"emtest/api-service/problem"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const AdminTokenHeader = "X-Admin-Token"
+
+// IsAdmin reports whether the request carries the configured admin token.
+// An empty token disables admin access altogether.
+func IsAdmin(c *fiber.Ctx, token string) bool {
+	provided := c.Get(AdminTokenHeader)
+	return token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// AdminAuth guards admin routes with the X-Admin-Token header.
+func AdminAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return problem.New(http.StatusForbidden, "Forbidden", "Admin API is disabled: admin.token is not configured").Send(c)
+		}
+		if !IsAdmin(c, token) {
+			return problem.New(http.StatusUnauthorized, "Unauthorized", "Valid "+AdminTokenHeader+" header is required").Send(c)
+		}
+		return c.Next()
+	}
+}
@@ -1,31 +1,178 @@
 package middleware
 
 import (
+	"bytes"
+	"emtest/api-service/config"
+	"emtest/api-service/requestid"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
 
-func Logger(logger *logrus.Logger) fiber.Handler {
+// Body logging modes of config.Logging.
+const (
+	BodiesOff       = "off"
+	BodiesTruncated = "truncated"
+	BodiesFull      = "full"
+)
+
+const (
+	defaultMaxBodySize = 1024
+	redactedValue      = "[REDACTED]"
+)
+
+// bodyLogger renders request and response bodies for the log.
+type bodyLogger struct {
+	mode        string
+	maxBodySize int
+	redact      map[string]bool
+}
+
+// Logger logs one entry per request. Bodies are left out unless cfg.Bodies
+// asks for them, and successful requests of the routes of cfg.Sampling are
+// logged at the configured rate.
+func Logger(logger *logrus.Logger, cfg config.Logging) (fiber.Handler, error) {
+	bodies := bodyLogger{mode: cfg.Bodies, maxBodySize: cfg.MaxBodySize, redact: make(map[string]bool)}
+	switch bodies.mode {
+	case "":
+		bodies.mode = BodiesOff
+	case BodiesOff, BodiesTruncated, BodiesFull:
+	default:
+		return nil, fmt.Errorf("logging.bodies must be one of %s, %s, %s", BodiesOff, BodiesTruncated, BodiesFull)
+	}
+	if bodies.maxBodySize <= 0 {
+		bodies.maxBodySize = defaultMaxBodySize
+	}
+	for _, field := range cfg.Redact {
+		bodies.redact[strings.ToLower(field)] = true
+	}
+
+	sampling := make(map[string]float64, len(cfg.Sampling))
+	for _, route := range cfg.Sampling {
+		if route.Rate < 0 || route.Rate > 1 {
+			return nil, fmt.Errorf("logging.sampling rate of %s must be between 0 and 1", route.Route)
+		}
+		sampling[route.Route] = route.Rate
+	}
+
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
-		requestBody := string(c.Body())
 		err := c.Next()
 		duration := time.Since(start)
 
-		logger.WithFields(logrus.Fields{
-			"method":        c.Method(),
-			"path":          c.Path(),
-			"status":        c.Response().StatusCode(),
-			"duration_ms":   duration.Milliseconds(),
-			"ip":            c.IP(),
-			"user_agent":    string(c.Request().Header.UserAgent()),
-			"request_body":  requestBody,
-			"response_body": string(c.Response().Body()),
-			"content_type":  string(c.Response().Header.ContentType()),
-		}).Info("HTTP Request")
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		route := c.Route().Path
+		if rate, sampled := sampling[route]; sampled && status < http.StatusBadRequest && rand.Float64() >= rate {
+			return err
+		}
 
+		fields := logrus.Fields{
+			"request_id":   requestid.From(c.UserContext()),
+			"method":       c.Method(),
+			"path":         c.Path(),
+			"route":        route,
+			"status":       status,
+			"duration_ms":  duration.Milliseconds(),
+			"ip":           c.IP(),
+			"user_agent":   string(c.Request().Header.UserAgent()),
+			"content_type": string(c.Response().Header.ContentType()),
+		}
+		if bodies.mode != BodiesOff {
+			fields["request_body"] = bodies.render(c.Body(), string(c.Request().Header.ContentType()))
+			fields["response_body"] = bodies.responseBody(c)
+		}
+
+		logger.WithFields(fields).Info("HTTP Request")
 		return err
+	}, nil
+}
+
+// responseBody renders the response body. Streamed bodies, such as exports,
+// are not read: that would buffer the whole stream in memory.
+func (b bodyLogger) responseBody(c *fiber.Ctx) string {
+	if c.Response().IsBodyStream() {
+		return "[stream]"
+	}
+	return b.render(c.Response().Body(), string(c.Response().Header.ContentType()))
+}
+
+// render returns body with the redacted fields masked, cut at the size cap in
+// truncated mode. Bodies other than JSON cannot be redacted and are only
+// described by their size.
+func (b bodyLogger) render(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !strings.Contains(contentType, "json") {
+		return fmt.Sprintf("[%d bytes]", len(body))
+	}
+
+	redacted, err := b.redactJSON(body)
+	if err != nil {
+		return fmt.Sprintf("[%d bytes of invalid JSON]", len(body))
+	}
+
+	if b.mode == BodiesTruncated && len(redacted) > b.maxBodySize {
+		// Cut before the rune the size cap falls into, to keep the log valid UTF-8.
+		n := b.maxBodySize
+		for n > 0 && !utf8.RuneStart(redacted[n]) {
+			n--
+		}
+		return fmt.Sprintf("%s...[%d bytes truncated]", redacted[:n], len(redacted)-n)
+	}
+	return redacted
+}
+
+func (b bodyLogger) redactJSON(body []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	if len(b.redact) == 0 {
+		return string(body), nil
+	}
+
+	redacted, err := json.Marshal(b.redactValue(value))
+	if err != nil {
+		return "", err
+	}
+	return string(redacted), nil
+}
+
+// redactValue masks the redacted fields of objects at any depth.
+func (b bodyLogger) redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if b.redact[strings.ToLower(key)] {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = b.redactValue(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = b.redactValue(item)
+		}
 	}
+	return value
 }
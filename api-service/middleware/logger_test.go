@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"emtest/api-service/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRequest serves a request to an echo route through the logger and
+// returns the entries it logged.
+func logRequest(t *testing.T, cfg config.Logging, path, body string) []*logrus.Entry {
+	logger, hook := test.NewNullLogger()
+	handler, err := Logger(logger, cfg)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(handler)
+	app.Post("/subscriptions", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(c.Body())
+	})
+	app.Get("/subscriptions/:id", func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	method := http.MethodPost
+	if body == "" {
+		method = http.MethodGet
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	return hook.AllEntries()
+}
+
+func TestLogger_Bodies(t *testing.T) {
+	body := `{"service_name":"Netflix","price":400,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","prices":[{"User_ID":"x"}]}`
+
+	entries := logRequest(t, config.Logging{}, "/subscriptions", body)
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Data, "request_body")
+	assert.NotContains(t, entries[0].Data, "response_body")
+	assert.Equal(t, "/subscriptions", entries[0].Data["route"])
+
+	entries = logRequest(t, config.Logging{Bodies: BodiesFull, Redact: []string{"user_id"}}, "/subscriptions", body)
+	require.Len(t, entries, 1)
+	redacted := `{"price":400,"prices":[{"User_ID":"[REDACTED]"}],"service_name":"Netflix","user_id":"[REDACTED]"}`
+	assert.Equal(t, redacted, entries[0].Data["request_body"])
+	assert.Equal(t, redacted, entries[0].Data["response_body"])
+
+	entries = logRequest(t, config.Logging{Bodies: BodiesTruncated, MaxBodySize: 10}, "/subscriptions", body)
+	require.Len(t, entries, 1)
+	assert.Equal(t, `{"service_...[104 bytes truncated]`, entries[0].Data["request_body"])
+
+	// The cap falls into the second byte of "Я".
+	entries = logRequest(t, config.Logging{Bodies: BodiesTruncated, MaxBodySize: 18}, "/subscriptions", `{"service_name":"Яндекс Плюс"}`)
+	require.Len(t, entries, 1)
+	assert.Equal(t, `{"service_name":"...[23 bytes truncated]`, entries[0].Data["request_body"])
+	assert.True(t, utf8.ValidString(entries[0].Data["request_body"].(string)))
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	cfg := config.Logging{Sampling: []config.RouteSampling{
+		{Route: "/subscriptions", Rate: 0},
+		{Route: "/subscriptions/:id", Rate: 0},
+	}}
+
+	assert.Empty(t, logRequest(t, cfg, "/subscriptions", `{}`))
+
+	// Failed requests are logged whatever the rate.
+	entries := logRequest(t, cfg, "/subscriptions/42", "")
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusNotFound, entries[0].Data["status"])
+
+	cfg.Sampling[0].Rate = 1
+	assert.Len(t, logRequest(t, cfg, "/subscriptions", `{}`), 1)
+}
+
+func TestLogger_InvalidConfig(t *testing.T) {
+	_, err := Logger(logrus.New(), config.Logging{Bodies: "some"})
+	assert.Error(t, err)
+
+	_, err = Logger(logrus.New(), config.Logging{Sampling: []config.RouteSampling{{Route: "/", Rate: 2}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"emtest/api-service/requestid"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestID takes the id of the request from the X-Request-ID header, or
+// generates one when the header is missing or malformed, echoes it in the
+// response and attaches it to the user context (see requestid.From).
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// The header value points into a buffer Fiber reuses for the next
+		// request, while the id may outlive it in the context.
+		id := utils.CopyString(c.Get(requestid.Header))
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(requestid.Header, id)
+		c.SetUserContext(requestid.With(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// validRequestID accepts non-empty ids of printable ASCII characters, so that
+// a client cannot inject anything into log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"emtest/api-service/requestid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestID())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(requestid.From(c.UserContext()))
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		accepted bool
+	}{
+		{"Provided", "support-ticket-42", true},
+		{"Missing", "", false},
+		{"Too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"Control characters", "id\nforged=line", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			id := resp.Header.Get(requestid.Header)
+			assert.Equal(t, id, string(body))
+			if tt.accepted {
+				assert.Equal(t, tt.header, id)
+				return
+			}
+			_, err = uuid.Parse(id)
+			assert.NoError(t, err)
+		})
+	}
+}
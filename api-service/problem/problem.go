@@ -0,0 +1,102 @@
+// Package problem writes error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"emtest/api-service/requestid"
+	"emtest/api-service/subscription"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// DefaultType is the problem type of problems described by their status alone.
+const DefaultType = "about:blank"
+
+// @description Error response following RFC 7807 (application/problem+json).
+// @description Errors lists the fields that failed validation, so that clients
+// @description can point at each of them. RequestID matches the X-Request-ID
+// @description response header and the log lines of the request.
+type Problem struct {
+	Type      string                    `json:"type" example:"about:blank"`
+	Title     string                    `json:"title" example:"Validation failed"`
+	Status    int                       `json:"status" example:"400"`
+	Detail    string                    `json:"detail,omitempty" example:"Field 'price' must be greater than 0"`
+	Instance  string                    `json:"instance,omitempty" example:"/api/v1/subscriptions"`
+	RequestID string                    `json:"request_id,omitempty" example:"7f8c1a52-5d3e-4c7a-9d61-3b1f1e2a9c44"`
+	Errors    []subscription.FieldError `json:"errors,omitempty"`
+}
+
+// New returns a problem of the default type.
+func New(status int, title, detail string) *Problem {
+	return &Problem{Type: DefaultType, Title: title, Status: status, Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Detail
+}
+
+// Send responds with the problem. Instance defaults to the request path and
+// RequestID to the id of the request.
+func (p *Problem) Send(c *fiber.Ctx) error {
+	response := *p
+	if response.Instance == "" {
+		response.Instance = c.Path()
+	}
+	if response.RequestID == "" {
+		response.RequestID = requestid.From(c.UserContext())
+	}
+	return c.Status(response.Status).JSON(response, ContentType)
+}
+
+// Validation returns the 400 problem of err failing validation, listing the
+// fields of subscription.ValidationErrors.
+func Validation(err error) *Problem {
+	var validationErrors subscription.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return New(fiber.StatusBadRequest, "Validation failed", err.Error())
+	}
+
+	messages := make([]string, len(validationErrors))
+	for i, err := range validationErrors {
+		messages[i] = fmt.Sprintf("Field '%s' %s", err.Field, err.Message)
+	}
+
+	p := New(fiber.StatusBadRequest, "Validation failed", strings.Join(messages, "; "))
+	p.Errors = validationErrors
+	return p
+}
+
+// Body returns the 400 problem of a request body that cannot be parsed. A
+// value of the wrong JSON type, or of the wrong format such as a malformed
+// month or UUID, is reported as an error of its field.
+func Body(err error) *Problem {
+	p := New(fiber.StatusBadRequest, "Invalid request body", err.Error())
+
+	var typeError *json.UnmarshalTypeError
+	var formatError *subscription.FormatError
+	switch {
+	case errors.As(err, &typeError) && typeError.Field != "":
+		p.Errors = []subscription.FieldError{{
+			Field:   typeError.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("expected %s, got a JSON %s", typeError.Type, typeError.Value),
+		}}
+	case errors.As(err, &formatError):
+		p.Errors = []subscription.FieldError{{
+			Field:   formatError.Field,
+			Rule:    "format",
+			Value:   formatError.Value,
+			Message: formatError.Err.Error(),
+		}}
+	}
+	return p
+}
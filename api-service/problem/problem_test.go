@@ -0,0 +1,87 @@
+package problem
+
+import (
+	"emtest/api-service/requestid"
+	"emtest/api-service/subscription"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation(t *testing.T) {
+	err := subscription.ValidationErrors{
+		{Field: "price", Rule: "gt", Value: 0, Message: "must be greater than 0"},
+		{Field: "user_id", Rule: "required", Message: "is required"},
+	}
+
+	p := Validation(err)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "Validation failed", p.Title)
+	assert.Equal(t, "Field 'price' must be greater than 0; Field 'user_id' is required", p.Detail)
+	assert.Equal(t, []subscription.FieldError(err), p.Errors)
+
+	p = Validation(errors.New("invalid start_date"))
+	assert.Equal(t, "invalid start_date", p.Detail)
+	assert.Empty(t, p.Errors)
+}
+
+func TestBody(t *testing.T) {
+	var body struct {
+		Price int `json:"price"`
+	}
+	err := json.Unmarshal([]byte(`{"price": "free"}`), &body)
+
+	p := Body(err)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	if assert.Len(t, p.Errors, 1) {
+		assert.Equal(t, "price", p.Errors[0].Field)
+		assert.Equal(t, "type", p.Errors[0].Rule)
+	}
+
+	data := []byte(`{"start_date": "13-2025"}`)
+	var sub subscription.Subscription
+	err = subscription.FieldFormatError(data, &sub, json.Unmarshal(data, &sub))
+
+	p = Body(err)
+	if assert.Len(t, p.Errors, 1) {
+		assert.Equal(t, "start_date", p.Errors[0].Field)
+		assert.Equal(t, "format", p.Errors[0].Rule)
+		assert.Equal(t, "13-2025", p.Errors[0].Value)
+	}
+
+	p = Body(errors.New("unexpected end of JSON input"))
+	assert.Empty(t, p.Errors)
+}
+
+func TestSend(t *testing.T) {
+	app := fiber.New()
+	app.Get("/subscriptions/:id", func(c *fiber.Ctx) error {
+		c.SetUserContext(requestid.With(c.UserContext(), "request-1"))
+		return New(http.StatusNotFound, "Not found", "Subscription not found").Send(c)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/subscriptions/42", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Not found",
+		"status": 404,
+		"detail": "Subscription not found",
+		"instance": "/subscriptions/42",
+		"request_id": "request-1"
+	}`, string(data))
+}
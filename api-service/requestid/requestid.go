@@ -0,0 +1,21 @@
+// Package requestid carries the id of the request that caused a piece of work,
+// so that log lines and error responses of the request can be matched.
+package requestid
+
+import "context"
+
+// Header is the HTTP header the request id is read from and echoed in.
+const Header = "X-Request-ID"
+
+type idKey struct{}
+
+// With returns a copy of ctx carrying the request id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// From returns the request id set by With, empty outside of a request.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
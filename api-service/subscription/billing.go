@@ -0,0 +1,120 @@
+package subscription
+
+import (
+	"fmt"
+	"math"
+)
+
+// BillingPeriod is how often the subscription price is charged.
+type BillingPeriod string
+
+const (
+	Weekly    BillingPeriod = "weekly"
+	Monthly   BillingPeriod = "monthly"
+	Quarterly BillingPeriod = "quarterly"
+	Yearly    BillingPeriod = "yearly"
+)
+
+// AccountingMode selects how a price charged for a billing period is attributed to months.
+type AccountingMode string
+
+const (
+	// Accrual spreads the price evenly over the months of the billing period,
+	// so a yearly 9000 plan contributes 750 to every month.
+	Accrual AccountingMode = "accrual"
+	// Cash attributes the full price to the months in which a payment is due,
+	// so a yearly 9000 plan contributes 9000 to its renewal month only.
+	Cash AccountingMode = "cash"
+)
+
+const weeksPerYear = 52
+
+func ParseAccountingMode(mode string) (AccountingMode, error) {
+	switch AccountingMode(mode) {
+	case "":
+		return Accrual, nil
+	case Accrual, Cash:
+		return AccountingMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid accounting mode %q: must be one of %s, %s", mode, Accrual, Cash)
+}
+
+// periodMonths returns the length of monthly, quarterly and yearly periods in months.
+func (p BillingPeriod) periodMonths() int {
+	switch p {
+	case Quarterly:
+		return 3
+	case Yearly:
+		return 12
+	}
+	return 1
+}
+
+// AmountIn returns the part of the price attributed to month, zero when the
+// subscription is not active in it.
+func (s Subscription) AmountIn(month Month, mode AccountingMode) float64 {
+	if !s.ActiveIn(month) {
+		return 0
+	}
+
+	price := float64(s.PriceAt(month))
+
+	if s.BillingPeriod == Weekly {
+		if mode == Cash {
+			return price * float64(s.weeklyChargesIn(month))
+		}
+		return price * weeksPerYear / 12
+	}
+
+	periodMonths := s.BillingPeriod.periodMonths()
+	if mode == Cash {
+		if (MonthsBetween(s.StartDate, month)-1)%periodMonths == 0 {
+			return price
+		}
+		return 0
+	}
+	return price / float64(periodMonths)
+}
+
+// weeklyChargesIn counts weekly payments falling into month, the first one
+// being due on the first day of the start month.
+func (s Subscription) weeklyChargesIn(month Month) int {
+	start := s.StartDate.Time()
+	monthStart := month.Time()
+	monthEnd := month.AddMonths(1).Time()
+
+	daysToMonth := int(monthStart.Sub(start).Hours() / 24)
+	first := 0
+	if daysToMonth > 0 {
+		first = (daysToMonth + 6) / 7
+	}
+
+	charges := 0
+	for charge := start.AddDate(0, 0, first*7); charge.Before(monthEnd); charge = charge.AddDate(0, 0, 7) {
+		charges++
+	}
+	return charges
+}
+
+// Cost returns the amount attributed to the months of the from..to window.
+// A zero from means the window has no lower bound.
+func (s Subscription) Cost(from, to Month, mode AccountingMode) float64 {
+	start := s.StartDate
+	if start.Before(from) {
+		start = from
+	}
+
+	total := 0.0
+	for month := start; !month.After(to); month = month.AddMonths(1) {
+		if s.EndDate != nil && !s.EndDate.IsZero() && month.After(*s.EndDate) {
+			break
+		}
+		total += s.AmountIn(month, mode)
+	}
+	return total
+}
+
+// RoundAmount rounds an amount to hundredths.
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
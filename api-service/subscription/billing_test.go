@@ -0,0 +1,66 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccountingMode(t *testing.T) {
+	mode, err := ParseAccountingMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, Accrual, mode)
+
+	mode, err = ParseAccountingMode("cash")
+	assert.NoError(t, err)
+	assert.Equal(t, Cash, mode)
+
+	_, err = ParseAccountingMode("daily")
+	assert.Error(t, err)
+}
+
+func TestAmountIn(t *testing.T) {
+	tests := []struct {
+		name   string
+		period BillingPeriod
+		month  string
+		mode   AccountingMode
+		want   float64
+	}{
+		{"Monthly accrual", Monthly, "05-2024", Accrual, 1200},
+		{"Monthly cash", Monthly, "05-2024", Cash, 1200},
+		{"Empty period is monthly", "", "05-2024", Cash, 1200},
+		{"Yearly accrual", Yearly, "05-2024", Accrual, 100},
+		{"Yearly cash renewal month", Yearly, "03-2025", Cash, 1200},
+		{"Yearly cash other month", Yearly, "04-2025", Cash, 0},
+		{"Quarterly accrual", Quarterly, "04-2024", Accrual, 400},
+		{"Quarterly cash renewal month", Quarterly, "06-2024", Cash, 1200},
+		{"Quarterly cash other month", Quarterly, "07-2024", Cash, 0},
+		{"Weekly accrual", Weekly, "04-2024", Accrual, 1200 * 52.0 / 12},
+		{"Weekly cash first month", Weekly, "03-2024", Cash, 1200 * 5},
+		{"Weekly cash next month", Weekly, "04-2024", Cash, 1200 * 4},
+		{"Before start", Monthly, "02-2024", Accrual, 0},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			sub := Subscription{Price: 1200, BillingPeriod: testCase.period, StartDate: mustMonth(t, "03-2024")}
+			assert.InDelta(t, testCase.want, sub.AmountIn(mustMonth(t, testCase.month), testCase.mode), 0.001)
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	endDate := mustMonth(t, "02-2026")
+	sub := Subscription{Price: 9000, BillingPeriod: Yearly, StartDate: mustMonth(t, "03-2024"), EndDate: &endDate}
+
+	assert.Equal(t, 750.0*12, sub.Cost(mustMonth(t, "01-2024"), mustMonth(t, "02-2025"), Accrual))
+	assert.Equal(t, 9000.0, sub.Cost(mustMonth(t, "01-2024"), mustMonth(t, "02-2025"), Cash))
+	assert.Equal(t, 18000.0, sub.Cost(Month{}, mustMonth(t, "12-2030"), Cash))
+	assert.Equal(t, 0.0, sub.Cost(mustMonth(t, "04-2026"), mustMonth(t, "12-2030"), Cash))
+}
+
+func TestRoundAmount(t *testing.T) {
+	assert.Equal(t, 83.33, RoundAmount(1000.0/12))
+	assert.Equal(t, 750.0, RoundAmount(750))
+}
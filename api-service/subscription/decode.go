@@ -0,0 +1,125 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FormatError is a JSON field whose value does not have the expected format,
+// such as a month other than MM-YYYY or a malformed UUID. Field is the JSON
+// path of the field, dot-separated for nested objects.
+type FormatError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// FieldFormatError returns err, the failure to unmarshal the JSON data into v,
+// as a *FormatError naming the field at fault. encoding/json only names the
+// field of type mismatches: a value rejected by its own UnmarshalJSON or
+// UnmarshalText, as Month and uuid.UUID do, comes without it. Other errors,
+// or values that cannot be traced back to a field, are returned as they are.
+func FieldFormatError(data []byte, v interface{}, err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if err == nil || errors.As(err, &syntaxError) || errors.As(err, &typeError) {
+		return err
+	}
+
+	if formatError := findFormatError(data, reflect.TypeOf(v)); formatError != nil {
+		return formatError
+	}
+	return err
+}
+
+// findFormatError looks for the field of data that fails to unmarshal into
+// its field of t.
+func findFormatError(data []byte, t reflect.Type) *FormatError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) != nil {
+			return nil
+		}
+		for name, field := range jsonFields(t) {
+			raw, exists := fields[name]
+			if !exists {
+				continue
+			}
+			err := json.Unmarshal(raw, reflect.New(field.Type).Interface())
+			if err == nil {
+				continue
+			}
+			if nested := findFormatError(raw, field.Type); nested != nil {
+				nested.Field = name + "." + nested.Field
+				return nested
+			}
+			return &FormatError{Field: name, Value: rawValue(raw), Err: err}
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for _, item := range items {
+			if formatError := findFormatError(item, t.Elem()); formatError != nil {
+				return formatError
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the fields of a struct type by JSON name, including the
+// promoted fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for name, promoted := range jsonFields(embedded) {
+					fields[name] = promoted
+				}
+				continue
+			}
+		}
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// rawValue returns a JSON string without its quotes and other values as they are.
+func rawValue(raw json.RawMessage) string {
+	var value string
+	if json.Unmarshal(raw, &value) == nil {
+		return value
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,51 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldFormatError(t *testing.T) {
+	type item struct {
+		Subscription Subscription `json:"subscription"`
+	}
+	type batch struct {
+		Items []item `json:"items"`
+	}
+
+	tests := []struct {
+		name  string
+		data  string
+		v     interface{}
+		field string
+		value string
+	}{
+		{"month", `{"start_date": "13-2025"}`, &Subscription{}, "start_date", "13-2025"},
+		{"uuid", `{"user_id": "1234"}`, &Subscription{}, "user_id", "1234"},
+		{"nested", `{"items": [{"subscription": {"end_date": "2025-01"}}]}`, &batch{}, "items.subscription.end_date", "2025-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FieldFormatError([]byte(tt.data), tt.v, json.Unmarshal([]byte(tt.data), tt.v))
+
+			var formatError *FormatError
+			if assert.ErrorAs(t, err, &formatError) {
+				assert.Equal(t, tt.field, formatError.Field)
+				assert.Equal(t, tt.value, formatError.Value)
+			}
+		})
+	}
+
+	var sub Subscription
+	err := json.Unmarshal([]byte(`{"price": "free"}`), &sub)
+	var typeError *json.UnmarshalTypeError
+	assert.ErrorAs(t, FieldFormatError([]byte(`{"price": "free"}`), &sub, err), &typeError)
+
+	assert.NoError(t, FieldFormatError(nil, &sub, nil))
+	other := errors.New("unexpected EOF")
+	assert.Equal(t, other, FieldFormatError([]byte(`{`), &sub, other))
+}
@@ -0,0 +1,150 @@
+package subscription
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Month is a calendar month. It is stored in the database as a date pointing
+// to the first day of the month and exchanged over the API as "MM-YYYY".
+type Month struct {
+	t time.Time
+}
+
+func NewMonth(year int, month time.Month) Month {
+	return Month{t: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func monthOf(t time.Time) Month {
+	return NewMonth(t.Year(), t.Month())
+}
+
+// ParseMonth parses an "MM-YYYY" string.
+func ParseMonth(dateStr string) (Month, error) {
+	if dateStr == "" {
+		return Month{}, fmt.Errorf("invalid date format: expected 'MM-YYYY'")
+	}
+	if err := ValidateDateFormat(dateStr); err != nil {
+		return Month{}, err
+	}
+
+	t, err := time.Parse(monthLayout, dateStr)
+	if err != nil {
+		return Month{}, err
+	}
+	return monthOf(t), nil
+}
+
+// Now returns the current time. Tests replace it to move the clock.
+var Now = time.Now
+
+// CurrentMonth returns the current month in UTC.
+func CurrentMonth() Month {
+	return monthOf(Now().UTC())
+}
+
+func (m Month) Time() time.Time {
+	return m.t
+}
+
+func (m Month) IsZero() bool {
+	return m.t.IsZero()
+}
+
+func (m Month) Before(other Month) bool {
+	return m.t.Before(other.t)
+}
+
+func (m Month) After(other Month) bool {
+	return m.t.After(other.t)
+}
+
+func (m Month) AddMonths(n int) Month {
+	return Month{t: m.t.AddDate(0, n, 0)}
+}
+
+func (m Month) String() string {
+	if m.IsZero() {
+		return ""
+	}
+	return m.t.Format(monthLayout)
+}
+
+func (m Month) MarshalJSON() ([]byte, error) {
+	if m.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(m.String())
+}
+
+func (m *Month) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Month{}
+		return nil
+	}
+
+	var dateStr string
+	if err := json.Unmarshal(data, &dateStr); err != nil {
+		return fmt.Errorf("invalid date format: expected 'MM-YYYY' string")
+	}
+	if dateStr == "" {
+		*m = Month{}
+		return nil
+	}
+
+	parsed, err := ParseMonth(dateStr)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m *Month) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Month{}
+	case time.Time:
+		*m = monthOf(v)
+	case string:
+		return m.scanString(v)
+	case []byte:
+		return m.scanString(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Month", value)
+	}
+	return nil
+}
+
+func (m *Month) scanString(value string) error {
+	for _, layout := range []string{time.DateOnly, time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", time.DateTime, monthLayout} {
+		if t, err := time.Parse(layout, value); err == nil {
+			*m = monthOf(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot scan %q into Month", value)
+}
+
+func (m Month) Value() (driver.Value, error) {
+	if m.IsZero() {
+		return nil, nil
+	}
+	return m.t, nil
+}
+
+func (Month) GormDataType() string {
+	return "date"
+}
+
+// MonthsBetween returns the number of calendar months from..to, both inclusive.
+// It returns 0 when to is before from.
+func MonthsBetween(from, to Month) int {
+	months := (to.t.Year()-from.t.Year())*12 + int(to.t.Month()) - int(from.t.Month()) + 1
+	if months < 0 {
+		return 0
+	}
+	return months
+}
@@ -0,0 +1,88 @@
+package subscription
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustMonth(t *testing.T, dateStr string) Month {
+	t.Helper()
+	month, err := ParseMonth(dateStr)
+	if err != nil {
+		t.Fatalf("Failed to parse month %q: %s", dateStr, err)
+	}
+	return month
+}
+
+func TestMonthsBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want int
+	}{
+		{"Same month", "01-2025", "01-2025", 1},
+		{"Within year", "01-2025", "12-2025", 12},
+		{"Across years", "11-2024", "02-2025", 4},
+		{"Reversed", "03-2025", "01-2025", 0},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.want, MonthsBetween(mustMonth(t, testCase.from), mustMonth(t, testCase.to)))
+		})
+	}
+}
+
+func TestMonthOrdering(t *testing.T) {
+	assert.True(t, mustMonth(t, "02-2024").Before(mustMonth(t, "01-2025")))
+	assert.True(t, mustMonth(t, "01-2025").After(mustMonth(t, "12-2024")))
+	assert.Equal(t, "01-2025", mustMonth(t, "11-2024").AddMonths(2).String())
+}
+
+func TestMonthJSON(t *testing.T) {
+	var sub struct {
+		StartDate Month  `json:"start_date"`
+		EndDate   *Month `json:"end_date,omitempty"`
+	}
+
+	err := json.Unmarshal([]byte(`{"start_date":"07-2024","end_date":null}`), &sub)
+	assert.NoError(t, err)
+	assert.Equal(t, NewMonth(2024, time.July), sub.StartDate)
+	assert.Nil(t, sub.EndDate)
+
+	body, err := json.Marshal(sub)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"start_date":"07-2024"}`, string(body))
+
+	for _, invalid := range []string{`"2024-07"`, `"13-2024"`, `"7-2024"`, `202407`} {
+		assert.Error(t, json.Unmarshal([]byte(`{"start_date":`+invalid+`}`), &sub), invalid)
+	}
+}
+
+func TestMonthScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  Month
+	}{
+		{"Time", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), NewMonth(2024, time.March)},
+		{"Date string", "2024-03-01", NewMonth(2024, time.March)},
+		{"Timestamp bytes", []byte("2024-03-01 00:00:00+00:00"), NewMonth(2024, time.March)},
+		{"Null", nil, Month{}},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			var month Month
+			assert.NoError(t, month.Scan(testCase.value))
+			assert.Equal(t, testCase.want, month)
+		})
+	}
+
+	var month Month
+	assert.Error(t, month.Scan(42))
+}
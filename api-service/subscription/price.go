@@ -0,0 +1,70 @@
+package subscription
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceChange is a subscription price effective from a given month on.
+type PriceChange struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"type:uuid;not null;uniqueIndex:idx_price_changes_subscription_month"`
+	EffectiveFrom  Month     `json:"effective_from" gorm:"not null;uniqueIndex:idx_price_changes_subscription_month" swaggertype:"string" format:"MM-YYYY" example:"01-2025"`
+	Price          int       `json:"price" example:"399"`
+	CreatedAt      time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP;autoCreateTime"`
+}
+
+// PriceHistory returns the price changes of the subscription ordered by
+// EffectiveFrom. Subscriptions without a recorded history get a single entry
+// with the current price effective from the start date.
+func (s Subscription) PriceHistory() []PriceChange {
+	if len(s.Prices) == 0 {
+		return []PriceChange{{SubscriptionID: s.ID, EffectiveFrom: s.StartDate, Price: s.Price}}
+	}
+
+	history := make([]PriceChange, len(s.Prices))
+	copy(history, s.Prices)
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].EffectiveFrom.Before(history[j].EffectiveFrom)
+	})
+	return history
+}
+
+// PriceAt returns the price in effect in month. Months before the first
+// recorded change use the earliest known price.
+func (s Subscription) PriceAt(month Month) int {
+	if len(s.Prices) == 0 {
+		return s.Price
+	}
+
+	var (
+		price int
+		from  Month
+		found bool
+	)
+	for _, change := range s.Prices {
+		if change.EffectiveFrom.After(month) {
+			continue
+		}
+		if !found || change.EffectiveFrom.After(from) {
+			price, from, found = change.Price, change.EffectiveFrom, true
+		}
+	}
+	if found {
+		return price
+	}
+
+	return s.PriceHistory()[0].Price
+}
+
+// CurrentPrice returns the price in effect in the current month, or in the
+// first month when the subscription starts later.
+func (s Subscription) CurrentPrice() int {
+	month := CurrentMonth()
+	if month.Before(s.StartDate) {
+		month = s.StartDate
+	}
+	return s.PriceAt(month)
+}
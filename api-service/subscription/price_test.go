@@ -0,0 +1,97 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceAt(t *testing.T) {
+	sub := Subscription{
+		Price:     500,
+		StartDate: mustMonth(t, "01-2024"),
+		Prices: []PriceChange{
+			{EffectiveFrom: mustMonth(t, "06-2024"), Price: 400},
+			{EffectiveFrom: mustMonth(t, "01-2024"), Price: 300},
+			{EffectiveFrom: mustMonth(t, "01-2025"), Price: 500},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		month string
+		want  int
+	}{
+		{"Before first change", "12-2023", 300},
+		{"First month", "01-2024", 300},
+		{"Before second change", "05-2024", 300},
+		{"Second change", "06-2024", 400},
+		{"Latest change", "03-2025", 500},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.want, sub.PriceAt(mustMonth(t, testCase.month)))
+		})
+	}
+
+	assert.Equal(t, 700, Subscription{Price: 700}.PriceAt(mustMonth(t, "01-2024")))
+}
+
+func TestCurrentPrice(t *testing.T) {
+	now := Now
+	defer func() { Now = now }()
+	Now = func() time.Time { return time.Date(2024, time.July, 15, 0, 0, 0, 0, time.UTC) }
+
+	sub := Subscription{
+		Price:     300,
+		StartDate: mustMonth(t, "01-2024"),
+		Prices: []PriceChange{
+			{EffectiveFrom: mustMonth(t, "01-2024"), Price: 300},
+			{EffectiveFrom: mustMonth(t, "06-2024"), Price: 400},
+			{EffectiveFrom: mustMonth(t, "01-2025"), Price: 500},
+		},
+	}
+	assert.Equal(t, 400, sub.CurrentPrice())
+
+	Now = func() time.Time { return time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, 500, sub.CurrentPrice())
+
+	future := Subscription{
+		Price:     600,
+		StartDate: mustMonth(t, "03-2025"),
+		Prices: []PriceChange{
+			{EffectiveFrom: mustMonth(t, "03-2025"), Price: 600},
+			{EffectiveFrom: mustMonth(t, "06-2025"), Price: 700},
+		},
+	}
+	assert.Equal(t, 600, future.CurrentPrice())
+}
+
+func TestPriceHistory(t *testing.T) {
+	sub := Subscription{Price: 700, StartDate: mustMonth(t, "01-2024")}
+	assert.Equal(t, []PriceChange{{EffectiveFrom: mustMonth(t, "01-2024"), Price: 700}}, sub.PriceHistory())
+
+	sub.Prices = []PriceChange{
+		{EffectiveFrom: mustMonth(t, "06-2024"), Price: 800},
+		{EffectiveFrom: mustMonth(t, "01-2024"), Price: 700},
+	}
+	history := sub.PriceHistory()
+	assert.Equal(t, "01-2024", history[0].EffectiveFrom.String())
+	assert.Equal(t, "06-2024", history[1].EffectiveFrom.String())
+	assert.Equal(t, "06-2024", sub.Prices[0].EffectiveFrom.String())
+}
+
+func TestCostWithPriceHistory(t *testing.T) {
+	sub := Subscription{
+		Price:     400,
+		StartDate: mustMonth(t, "01-2024"),
+		Prices: []PriceChange{
+			{EffectiveFrom: mustMonth(t, "01-2024"), Price: 300},
+			{EffectiveFrom: mustMonth(t, "04-2024"), Price: 400},
+		},
+	}
+
+	assert.Equal(t, 300.0*3+400*3, sub.Cost(mustMonth(t, "01-2024"), mustMonth(t, "06-2024"), Accrual))
+}
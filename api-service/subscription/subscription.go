@@ -10,16 +10,23 @@ import (
 )
 
 type Subscription struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	ServiceName string    `json:"service_name" validate:"required"`
-	Price       int       `json:"price" validate:"required,gt=0"`
-	UserId      uuid.UUID `json:"user_id" validate:"required"`
-	StartDate   string    `json:"start_date" validate:"required"`
-	EndDate     *string   `json:"end_date,omitempty"`
-	CreatedAt   time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP;autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP;autoUpdateTime"`
+	ID            uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey"`
+	ServiceName   string        `json:"service_name" validate:"required"`
+	Price         int           `json:"price" validate:"required,gt=0"`
+	Currency      string        `json:"currency" gorm:"type:varchar(3);default:RUB" validate:"omitempty,iso4217" example:"RUB"`
+	BillingPeriod BillingPeriod `json:"billing_period" gorm:"default:monthly" validate:"omitempty,oneof=weekly monthly quarterly yearly" enums:"weekly,monthly,quarterly,yearly" example:"monthly"`
+	UserId        uuid.UUID     `json:"user_id" validate:"required"`
+	StartDate     Month         `json:"start_date" validate:"required" swaggertype:"string" format:"MM-YYYY" example:"01-2025"`
+	EndDate       *Month        `json:"end_date,omitempty" swaggertype:"string" format:"MM-YYYY" example:"12-2025"`
+	AllowOverlap  bool          `json:"allow_overlap" gorm:"not null;default:false"`
+	CreatedAt     time.Time     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP;autoCreateTime"`
+	UpdatedAt     time.Time     `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP;autoUpdateTime"`
+	DeletedAt     *time.Time    `json:"deleted_at,omitempty" gorm:"index"`
+	Prices        []PriceChange `json:"-" gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE"`
 }
 
+const monthLayout = "01-2006"
+
 func ValidateDateFormat(dateStr string) error {
 	if dateStr == "" {
 		return nil
@@ -48,10 +55,30 @@ func ValidateDateFormat(dateStr string) error {
 		return fmt.Errorf("invalid year: must be between 1900 and 9999")
 	}
 
-	_, err = time.Parse("01-2006", dateStr)
+	_, err = time.Parse(monthLayout, dateStr)
 	if err != nil {
 		return fmt.Errorf("invalid date format: %v", err)
 	}
 
 	return nil
 }
+
+// ActiveMonths returns the number of billing months the subscription overlaps
+// the from..to window. A zero from means the window has no lower bound,
+// an open-ended subscription is considered active up to the window end.
+func (s Subscription) ActiveMonths(from, to Month) int {
+	start, end := s.StartDate, to
+	if s.EndDate != nil && !s.EndDate.IsZero() && s.EndDate.Before(end) {
+		end = *s.EndDate
+	}
+	if start.Before(from) {
+		start = from
+	}
+
+	return MonthsBetween(start, end)
+}
+
+// ActiveIn reports whether the subscription is active in the given month.
+func (s Subscription) ActiveIn(month Month) bool {
+	return s.ActiveMonths(month, month) == 1
+}
@@ -36,3 +36,43 @@ func TestValidateDateFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestActiveMonths(t *testing.T) {
+	endDate := mustMonth(t, "06-2024")
+
+	tests := []struct {
+		name string
+		sub  Subscription
+		from string
+		to   string
+		want int
+	}{
+		{"Open-ended inside window", Subscription{StartDate: mustMonth(t, "03-2024")}, "01-2024", "12-2024", 10},
+		{"Open-ended started before window", Subscription{StartDate: mustMonth(t, "01-2023")}, "01-2024", "03-2024", 3},
+		{"Ends inside window", Subscription{StartDate: mustMonth(t, "01-2024"), EndDate: &endDate}, "03-2024", "12-2024", 4},
+		{"Starts after window", Subscription{StartDate: mustMonth(t, "01-2025")}, "01-2024", "12-2024", 0},
+		{"Ended before window", Subscription{StartDate: mustMonth(t, "01-2024"), EndDate: &endDate}, "07-2024", "12-2024", 0},
+		{"No window start", Subscription{StartDate: mustMonth(t, "10-2024")}, "", "12-2024", 3},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			var from Month
+			if testCase.from != "" {
+				from = mustMonth(t, testCase.from)
+			}
+
+			assert.Equal(t, testCase.want, testCase.sub.ActiveMonths(from, mustMonth(t, testCase.to)))
+		})
+	}
+}
+
+func TestActiveIn(t *testing.T) {
+	endDate := mustMonth(t, "03-2024")
+	sub := Subscription{StartDate: mustMonth(t, "01-2024"), EndDate: &endDate}
+
+	assert.False(t, sub.ActiveIn(mustMonth(t, "12-2023")))
+	assert.True(t, sub.ActiveIn(mustMonth(t, "01-2024")))
+	assert.True(t, sub.ActiveIn(mustMonth(t, "03-2024")))
+	assert.False(t, sub.ActiveIn(mustMonth(t, "04-2024")))
+}
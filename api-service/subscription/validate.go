@@ -0,0 +1,142 @@
+package subscription
+
+import (
+	"emtest/api-service/currency"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = NewValidator()
+
+// FieldError is a field that failed a validation rule. Field is the JSON name
+// of the field and Value the rejected value.
+type FieldError struct {
+	Field   string      `json:"field" example:"end_date"`
+	Rule    string      `json:"rule" example:"gtefield"`
+	Value   interface{} `json:"value" swaggertype:"string" example:"12-2024"`
+	Message string      `json:"message" example:"must not be before start_date"`
+}
+
+// ValidationErrors lists the fields that failed validation.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = fmt.Sprintf("%s %s", err.Field, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator checks values against validate tags and reports failures as
+// ValidationErrors named after the JSON fields.
+type Validator struct {
+	validate *validator.Validate
+}
+
+// NewValidator returns a validator that treats a zero Month as empty, so that
+// tags such as required reject it, and checks that a subscription does not
+// end before it starts.
+func NewValidator() *Validator {
+	v := validator.New()
+	v.RegisterCustomTypeFunc(validateMonth, Month{})
+	v.RegisterTagNameFunc(jsonName)
+	v.RegisterStructValidation(validateDates, Subscription{})
+	return &Validator{validate: v}
+}
+
+// Struct validates the fields of a struct.
+func (v *Validator) Struct(s interface{}) error {
+	return fieldErrors(v.validate.Struct(s))
+}
+
+// Var validates a single value against tag.
+func (v *Validator) Var(field interface{}, tag string) error {
+	return fieldErrors(v.validate.Var(field, tag))
+}
+
+func validateMonth(field reflect.Value) interface{} {
+	if month, ok := field.Interface().(Month); ok && !month.IsZero() {
+		return month.String()
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// validateDates rejects an end date before the start date.
+func validateDates(sl validator.StructLevel) {
+	s := sl.Current().Interface().(Subscription)
+	if s.EndDate != nil && !s.EndDate.IsZero() && s.EndDate.Before(s.StartDate) {
+		sl.ReportError(*s.EndDate, "end_date", "EndDate", "gtefield", "start_date")
+	}
+}
+
+// fieldErrors turns validator.ValidationErrors into ValidationErrors and
+// passes other errors through.
+func fieldErrors(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	result := make(ValidationErrors, len(validationErrors))
+	for i, err := range validationErrors {
+		result[i] = FieldError{Field: err.Field(), Rule: err.Tag(), Value: err.Value(), Message: ruleMessage(err)}
+	}
+	return result
+}
+
+// ruleMessage describes the rule a field failed.
+func ruleMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", err.Param())
+	case "iso4217":
+		return "must be an ISO 4217 currency code"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(err.Param(), " ", ", "))
+	case "gtefield":
+		return fmt.Sprintf("must not be before %s", err.Param())
+	case "excluded_without":
+		return fmt.Sprintf("is only allowed along with %s", strings.ToLower(err.Param()))
+	}
+	return fmt.Sprintf("failed validation: %s", err.Tag())
+}
+
+// Validate checks the subscription as a complete one. Failures are
+// ValidationErrors.
+func (s Subscription) Validate() error {
+	return validate.Struct(s)
+}
+
+// Prepare validates the subscription the way it is created or replaced
+// through the API and fills in the default currency and billing period.
+// Failures are ValidationErrors.
+func (s *Subscription) Prepare() error {
+	s.Currency = strings.ToUpper(s.Currency)
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if s.BillingPeriod == "" {
+		s.BillingPeriod = Monthly
+	}
+	if s.Currency == "" {
+		s.Currency = currency.Base
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package subscription
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepare(t *testing.T) {
+	sub := Subscription{ServiceName: "Netflix", Price: 400, Currency: "usd", UserId: uuid.New(), StartDate: NewMonth(2025, 1)}
+	assert.NoError(t, sub.Prepare())
+	assert.Equal(t, "USD", sub.Currency)
+	assert.Equal(t, Monthly, sub.BillingPeriod)
+
+	sub = Subscription{ServiceName: "Netflix", Price: 400, UserId: uuid.New(), StartDate: NewMonth(2025, 1)}
+	assert.NoError(t, sub.Prepare())
+	assert.Equal(t, "RUB", sub.Currency)
+
+	invalid := Subscription{ServiceName: "Netflix", Price: 400, BillingPeriod: "daily", UserId: uuid.New()}
+	err := invalid.Prepare()
+
+	var validationErrors ValidationErrors
+	if assert.True(t, errors.As(err, &validationErrors)) {
+		fields := make([]string, len(validationErrors))
+		for i, err := range validationErrors {
+			fields[i] = err.Field
+		}
+		assert.ElementsMatch(t, []string{"billing_period", "start_date"}, fields)
+	}
+}
+
+func TestValidate_Dates(t *testing.T) {
+	endDate := NewMonth(2024, 12)
+	sub := Subscription{ServiceName: "Netflix", Price: 400, UserId: uuid.New(), StartDate: NewMonth(2025, 1), EndDate: &endDate}
+
+	err := sub.Validate()
+	assert.Equal(t, ValidationErrors{{
+		Field:   "end_date",
+		Rule:    "gtefield",
+		Value:   "12-2024",
+		Message: "must not be before start_date",
+	}}, err)
+	assert.EqualError(t, err, "end_date must not be before start_date")
+
+	endDate = NewMonth(2025, 1)
+	assert.NoError(t, sub.Validate())
+}
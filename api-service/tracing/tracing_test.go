@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"emtest/api-service/config"
+	"emtest/api-service/db"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider recording the spans of the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	result := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		result[kv.Key] = kv.Value
+	}
+	return result
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+	shutdown, err := Setup(context.Background(), config.Tracing{})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	conn, err := db.Open(config.Database{Driver: db.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := conn.DB()
+		sqlDB.Close()
+	})
+	require.NoError(t, conn.Use(GormPlugin()))
+	require.NoError(t, conn.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)").Error)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		var count int64
+		if err := conn.WithContext(c.UserContext()).Table("items").Where("id = ?", c.Params("id")).Count(&count).Error; err != nil {
+			return err
+		}
+		return c.JSON(count)
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		return conn.WithContext(c.UserContext()).Exec("SELECT * FROM missing").Error
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/broken", nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The first span is the one of creating the table.
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+	query, request, failedQuery, failedRequest := spans[1], spans[2], spans[3], spans[4]
+
+	assert.Equal(t, "GET /items/:id", request.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+	assert.Equal(t, int64(200), attributes(request)["http.response.status_code"].AsInt64())
+
+	assert.Equal(t, "gorm.query items", query.Name())
+	assert.Equal(t, request.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, "sqlite", attributes(query)["db.system"].AsString())
+	assert.Contains(t, attributes(query)["db.query.text"].AsString(), "WHERE id = ?")
+
+	assert.Equal(t, codes.Error, failedQuery.Status().Code)
+	assert.Equal(t, failedRequest.SpanContext().SpanID(), failedQuery.Parent().SpanID())
+	assert.Equal(t, codes.Error, failedRequest.Status().Code)
+	assert.Equal(t, int64(500), attributes(failedRequest)["http.response.status_code"].AsInt64())
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), config.Tracing{Exporter: ExporterFile, Path: path})
+	require.NoError(t, err)
+
+	_, span := tracer().Start(context.Background(), "test")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"test"`)
+	assert.Contains(t, string(data), "subscriptions-api")
+
+	_, err = Setup(context.Background(), config.Tracing{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
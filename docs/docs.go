@@ -15,9 +15,271 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/audit": {
+            "get": {
+                "description": "Журнал изменений всех подписок с фильтрами",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter by subscription ID (UUID format)",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore",
+                            "purge"
+                        ],
+                        "type": "string",
+                        "description": "Filter by action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only entries created at or after this time (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only entries created before this time (RFC 3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from pagination.next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Audit entries, oldest first",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filters or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/rates": {
+            "get": {
+                "description": "Курсы валют, используемые для пересчета стоимости подписок. Курс задается в базовой валюте (RUB) за единицу валюты",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List exchange rates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exchange rates",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/currency.Rate"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Создание или обновление курса валюты",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set exchange rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Exchange rate",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/currency.Rate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saved exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/currency.Rate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление курса валюты",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete exchange rate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "USD",
+                        "description": "Currency code (ISO 4217)",
+                        "name": "currency",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success message",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - missing currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Exchange rate not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/subscriptions": {
             "get": {
-                "description": "Получение подписки по её id. Если id не указано, возвращаются все",
+                "description": "Страница подписок с фильтрами, сортировкой и курсорной пагинацией.\nПараметр id устарел: используйте GET /api/v1/subscriptions/{id}",
                 "consumes": [
                     "application/json"
                 ],
@@ -32,34 +294,117 @@ const docTemplate = `{
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "Subscription ID (UUID format)",
+                        "description": "Deprecated: Subscription ID (UUID format)",
                         "name": "id",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Only subscriptions active on or after this month (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Only subscriptions active on or before this month (MM-YYYY format)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "price",
+                            "start_date"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from pagination.next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Single subscription when ID provided",
+                        "description": "Page of subscriptions, or the subscription itself with the deprecated id",
                         "schema": {
-                            "$ref": "#/definitions/subscription.Subscription"
+                            "$ref": "#/definitions/handlers.SubscriptionsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filters, pagination parameters or ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Subscription not found",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Обновление подписки по её id",
+                "description": "Устаревший вариант PATCH /subscriptions/{id}: частичное обновление подписки, id передается в query.\nОтвет содержит заголовки Deprecation и Link на новый маршрут",
                 "consumes": [
                     "application/json"
                 ],
@@ -69,7 +414,8 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Update subscription",
+                "summary": "Update subscription by query id",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -97,21 +443,33 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad request - missing ID or invalid body",
+                        "description": "Bad request - missing ID, invalid body or end_date before start_date",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Subscription not found",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlapping subscription",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Создание новой подписки",
+                "description": "Создание новой подписки. end_date не может быть раньше start_date.\nПодписка не может пересекаться по месяцам с другой активной подпиской пользователя на тот же сервис, если ни у одной из них не указан allow_overlap",
                 "consumes": [
                     "application/json"
                 ],
@@ -143,19 +501,25 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlapping subscription",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Удаление подписки по её id",
+                "description": "Устаревший вариант DELETE /subscriptions/{id}, id передается в query.\nОтвет содержит заголовки Deprecation и Link на новый маршрут",
                 "consumes": [
                     "application/json"
                 ],
@@ -165,12 +529,12 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Delete subscription",
+                "summary": "Delete subscription by query id",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
                         "description": "Subscription ID (UUID format)",
                         "name": "id",
                         "in": "query",
@@ -187,27 +551,27 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request - missing ID",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "404": {
                         "description": "Subscription not found",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/subscriptions/calculate": {
-            "get": {
-                "description": "Подсчет стоимости подписки по фильтрам",
+        "/api/v1/subscriptions/bulk": {
+            "post": {
+                "description": "Выполнение нескольких операций над подписками за один запрос. Каждая операция проверяется так же, как на своем эндпоинте.\nВ режиме atomic операции выполняются в одной транзакции: при ошибке любой из них не применяется ни одна (ответ 422).\nВ режиме best_effort применяются все корректные операции, результат каждой указан в results",
                 "consumes": [
                     "application/json"
                 ],
@@ -217,35 +581,111 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Calculate total cost of subscriptions",
+                "summary": "Bulk create, update and delete subscriptions",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "example": "550e8400-e29b-41d4-a716-446655440000",
-                        "description": "Filter by user ID (UUID format)",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by service name",
-                        "name": "service_name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
+                        "description": "Operations to apply",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Results of the operations",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body, mode or number of operations",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Atomic request not applied, see results",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/calculate": {
+            "get": {
+                "description": "Подсчет стоимости подписок за период: цена умножается на число месяцев, в которые подписка активна внутри периода.\nЕсли end_date не указан, период заканчивается текущим месяцем, а если start_date в будущем — месяцем start_date. Если start_date не указан, период начинается с начала подписки.\nСуммы пересчитываются в target_currency по курсам из /api/v1/admin/rates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Calculate total cost of subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
                         "format": "MM-YYYY",
-                        "description": "Filter by start date (MM-YYYY format)",
+                        "description": "Period start (MM-YYYY format)",
                         "name": "start_date",
                         "in": "query"
                     },
                     {
                         "type": "string",
                         "format": "MM-YYYY",
-                        "description": "Filter by end date (MM-YYYY format)",
+                        "description": "Period end (MM-YYYY format)",
                         "name": "end_date",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Currency of the result (ISO 4217)",
+                        "name": "target_currency",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -255,10 +695,1146 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.SuccessCostResponse"
                         }
                     },
+                    "400": {
+                        "description": "Invalid period, accounting mode or currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/calculate/breakdown": {
+            "get": {
+                "description": "Подсчет стоимости подписок по тем же фильтрам, что и /calculate, с группировкой по сервису, пользователю или по обоим",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Cost breakdown of subscriptions",
+                "parameters": [
+                    {
+                        "enum": [
+                            "service_name",
+                            "user_id",
+                            "both"
+                        ],
+                        "type": "string",
+                        "default": "service_name",
+                        "description": "Grouping key",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period start (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period end (MM-YYYY format)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Currency of the result (ISO 4217)",
+                        "name": "target_currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Grouped totals with the grand total",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CostBreakdownResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period, grouping, accounting mode or currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/calculate/breakdown/export": {
+            "get": {
+                "description": "Выгрузка разбивки стоимости подписок (как в /calculate/breakdown) в CSV или JSON Lines, по строке на группу",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Export cost breakdown",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "service_name",
+                            "user_id",
+                            "both"
+                        ],
+                        "type": "string",
+                        "default": "service_name",
+                        "description": "Grouping key",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period start (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period end (MM-YYYY format)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Currency of the result (ISO 4217)",
+                        "name": "target_currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One group per row or line, largest first",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format, period, grouping, accounting mode or currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/calculate/monthly": {
+            "get": {
+                "description": "Сумма к оплате по каждому календарному месяцу периода по тем же фильтрам, что и /calculate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Monthly cost time series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period start (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period end (MM-YYYY format), current month by default",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Currency of the result (ISO 4217)",
+                        "name": "target_currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One row per month of the period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MonthlyCostResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period, accounting mode or currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/export": {
+            "get": {
+                "description": "Выгрузка всех подписок, подходящих под фильтры, в CSV или JSON Lines.\nОтвет передается потоком, подписки читаются из базы порциями",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Export subscriptions",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter by user ID (UUID format)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Only subscriptions active on or after this month (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Only subscriptions active on or before this month (MM-YYYY format)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "price",
+                            "start_date"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscriptions, one per row or line",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format, filters or sort parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/import": {
+            "post": {
+                "description": "Загрузка подписок из CSV или XLSX (первый лист). Первая строка содержит названия колонок:\nservice_name, price, user_id, start_date и необязательные currency, billing_period, end_date.\nКаждая строка проверяется так же, как при создании подписки. Если хотя бы одна строка некорректна, ничего не импортируется",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Import subscriptions from a CSV or XLSX file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV or XLSX file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "File format, by the file extension by default",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the file",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or unreadable file",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid rows, nothing imported",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Report"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/prices": {
+            "get": {
+                "description": "Устаревший вариант GET /subscriptions/{id}/prices, id передается в query.\nОтвет содержит заголовки Deprecation и Link на новый маршрут",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription price history by query id",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: return the history even if the subscription is deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price changes ordered by effective month",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription.PriceChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - missing ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}": {
+            "get": {
+                "description": "Получение подписки по её id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: return the subscription even if it is deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Полная замена подписки по её id. Поля, которые не переданы, сбрасываются (end_date) или принимают значения по умолчанию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Replace subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New subscription state",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription.Subscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Replaced subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - malformed ID, invalid body or end_date before start_date",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlapping subscription",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаление подписки по её id. Подписка помечается удаленной и может быть восстановлена до истечения срока хранения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Delete subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "example": "550e8400-e29b-41d4-a716-446655440000",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success message",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - malformed ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Частичное обновление подписки по её id: изменяются только переданные поля.\nОбновленная подписка проверяется целиком: end_date не может быть раньше start_date, в том числе сохраненного",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Update subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Subscription object with updated fields",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - malformed ID, invalid body or end_date before start_date",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Overlapping subscription",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}/history": {
+            "get": {
+                "description": "Журнал изменений подписки: кто, когда и какие поля изменил, со старыми и новыми значениями.\nДоступен и для удаленных подписок, в том числе окончательно удаленных. Для подписок, созданных до появления журнала, история пуста",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 500,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from pagination.next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Audit entries, oldest first",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuditPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed ID or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}/prices": {
+            "get": {
+                "description": "История изменения цены подписки. Подсчет стоимости использует цену, действовавшую в каждом месяце",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get subscription price history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: return the history even if the subscription is deleted",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Price changes ordered by effective month",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription.PriceChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - malformed ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/subscriptions/{id}/restore": {
+            "post": {
+                "description": "Восстановление удаленной подписки по её id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Restore subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Subscription ID (UUID format)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - malformed ID",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Subscription is not deleted or overlaps another subscription",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{user_id}/statement": {
+            "get": {
+                "description": "Печатная HTML-выписка по подпискам пользователя за период: каждая подписка со стоимостью в target_currency и итог.\nСтраница рассчитана на печать и сохранение в PDF из браузера",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get user statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID (UUID format)",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Admin only: include deleted subscriptions",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period start (MM-YYYY format)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "MM-YYYY",
+                        "description": "Period end (MM-YYYY format)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "accrual",
+                            "cash"
+                        ],
+                        "type": "string",
+                        "default": "accrual",
+                        "description": "Accounting mode: accrual spreads a price over its billing period, cash counts it in the months it is charged",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "RUB",
+                        "description": "Currency of the statement (ISO 4217)",
+                        "name": "target_currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML statement",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed user ID, invalid period, accounting mode or currency",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted without admin token",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing exchange rate",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Процесс запущен и отвечает на запросы. Зависимости не проверяются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Готовность принимать запросы: проверка каждой зависимости (доступность базы данных, версия схемы) с таймаутом.\nЕсли хотя бы одна зависимость недоступна, возвращается 503",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "Every dependency is up",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Some dependency is down",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
                         }
                     }
                 }
@@ -266,15 +1842,304 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "handlers.ErrorResponse": {
-            "description": "Error response object",
+        "audit.Action": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete",
+                "restore",
+                "purge"
+            ],
+            "x-enum-varnames": [
+                "Create",
+                "Update",
+                "Delete",
+                "Restore",
+                "Purge"
+            ]
+        },
+        "audit.Change": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "price"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete",
+                        "restore",
+                        "purge"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/audit.Action"
+                        }
+                    ],
+                    "example": "update"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "support@example.com"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Change"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "currency.Rate": {
+            "type": "object",
+            "required": [
+                "currency",
+                "rate"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 92.5
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AuditPage": {
+            "description": "Page of audit log entries",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Entry"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/handlers.Pagination"
+                }
+            }
+        },
+        "handlers.BulkOperation": {
+            "description": "One operation of a bulk request. Create takes subscription, update takes id and changes, delete takes id.",
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "$ref": "#/definitions/handlers.UpdateSubscriptionRequest"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ],
+                    "example": "create"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/subscription.Subscription"
+                }
+            }
+        },
+        "handlers.BulkRequest": {
+            "description": "Bulk request object",
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "default": "atomic",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BulkOperation"
+                    }
+                }
+            }
+        },
+        "handlers.BulkResponse": {
+            "description": "Bulk response object",
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BulkResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.BulkResult": {
+            "description": "Outcome of one bulk operation. Status is the HTTP status the operation would have had on its own endpoint, 424 for operations rolled back because another one failed.",
             "type": "object",
             "properties": {
                 "error": {
+                    "$ref": "#/definitions/problem.Problem"
+                },
+                "id": {
                     "type": "string"
                 },
-                "message": {
+                "index": {
+                    "type": "integer"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "subscription": {
+                    "$ref": "#/definitions/subscription.Subscription"
+                }
+            }
+        },
+        "handlers.CostBreakdownResponse": {
+            "description": "Cost breakdown object",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "group_by": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CostGroup"
+                    }
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.CostGroup": {
+            "description": "Cost of one group of subscriptions",
+            "type": "object",
+            "properties": {
+                "service_name": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MonthlyCost": {
+            "description": "Amount due in one calendar month",
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2025"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.MonthlyCostResponse": {
+            "description": "Monthly cost time series object",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MonthlyCost"
+                    }
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.Pagination": {
+            "description": "Pagination metadata",
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "next_cursor": {
                     "type": "string"
+                },
+                "order": {
+                    "type": "string",
+                    "example": "asc"
+                },
+                "sort": {
+                    "type": "string",
+                    "example": "created_at"
+                }
+            }
+        },
+        "handlers.SubscriptionsPage": {
+            "description": "Page of subscriptions",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription.Subscription"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/handlers.Pagination"
                 }
             }
         },
@@ -282,8 +2147,12 @@ const docTemplate = `{
             "description": "Success calculate object",
             "type": "object",
             "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
                 "total": {
-                    "type": "integer"
+                    "type": "number"
                 }
             }
         },
@@ -300,23 +2169,227 @@ const docTemplate = `{
         "handlers.UpdateSubscriptionRequest": {
             "type": "object",
             "properties": {
+                "allow_overlap": {
+                    "type": "boolean"
+                },
+                "billing_period": {
+                    "enum": [
+                        "weekly",
+                        "monthly",
+                        "quarterly",
+                        "yearly"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/subscription.BillingPeriod"
+                        }
+                    ]
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
                 "end_date": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "12-2025"
                 },
                 "price": {
                     "type": "integer"
                 },
+                "price_effective_from": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "03-2025"
+                },
                 "service_name": {
                     "type": "string"
                 },
                 "start_date": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2025"
                 },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
+        "health.CheckResult": {
+            "description": "State of a dependency along with how long checking it took.",
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "number",
+                    "example": 1.2
+                },
+                "error": {
+                    "type": "string",
+                    "example": "context deadline exceeded"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ],
+                    "example": "up"
+                }
+            }
+        },
+        "health.Response": {
+            "description": "State of the service and, for readiness, of each of its dependencies.",
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "down"
+                    ],
+                    "example": "up"
+                }
+            }
+        },
+        "importer.Report": {
+            "description": "Import report object",
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/importer.RowError"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "importer.RowError": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "string",
+                    "example": "price"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be greater than 0"
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "problem.Problem": {
+            "description": "Error response following RFC 7807 (application/problem+json). Errors lists the fields that failed validation, so that clients can point at each of them. RequestID matches the X-Request-ID response header and the log lines of the request.",
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "Field 'price' must be greater than 0"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/subscriptions"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "7f8c1a52-5d3e-4c7a-9d61-3b1f1e2a9c44"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "subscription.BillingPeriod": {
+            "type": "string",
+            "enum": [
+                "weekly",
+                "monthly",
+                "quarterly",
+                "yearly"
+            ],
+            "x-enum-varnames": [
+                "Weekly",
+                "Monthly",
+                "Quarterly",
+                "Yearly"
+            ]
+        },
+        "subscription.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "end_date"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must not be before start_date"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "gtefield"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "12-2024"
+                }
+            }
+        },
+        "subscription.PriceChange": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_from": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2025"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 399
+                },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
         "subscription.Subscription": {
             "type": "object",
             "required": [
@@ -326,12 +2399,38 @@ const docTemplate = `{
                 "user_id"
             ],
             "properties": {
+                "allow_overlap": {
+                    "type": "boolean"
+                },
+                "billing_period": {
+                    "enum": [
+                        "weekly",
+                        "monthly",
+                        "quarterly",
+                        "yearly"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/subscription.BillingPeriod"
+                        }
+                    ],
+                    "example": "monthly"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "end_date": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "deleted_at": {
                     "type": "string"
                 },
+                "end_date": {
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "12-2025"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -342,7 +2441,9 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "start_date": {
-                    "type": "string"
+                    "type": "string",
+                    "format": "MM-YYYY",
+                    "example": "01-2025"
                 },
                 "updated_at": {
                     "type": "string"
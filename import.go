@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"emtest/api-service/audit"
+	"emtest/api-service/config"
+	"emtest/api-service/db"
+	"emtest/api-service/importer"
+
+	"github.com/sirupsen/logrus"
+)
+
+const importUsage = "usage: app import [-dry-run] [-format csv|xlsx] <file>"
+
+// importActor is recorded in the audit log for subscriptions created by the
+// import subcommand.
+const importActor = "import-cli"
+
+// runImport implements the import subcommand: it validates every row of a CSV
+// or XLSX file and, unless -dry-run is given or a row is invalid, creates the
+// subscriptions in one transaction.
+func runImport(database config.Database, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "only validate the file")
+	format := flags.String("format", "", "file format, by the file extension by default")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	path := flags.Arg(0)
+
+	if database.Driver == db.DriverMemory {
+		return fmt.Errorf("cannot import into the %s driver: the subscriptions would be lost when the command exits", db.DriverMemory)
+	}
+
+	fileFormat, err := importer.ParseFormat(*format, path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	store, err := db.NewStore(database)
+	if err != nil {
+		return err
+	}
+
+	ctx := audit.WithActor(context.Background(), importActor)
+	report, err := importer.Import(ctx, store, file, fileFormat, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, rowError := range report.Errors {
+		if rowError.Column != "" {
+			fmt.Printf("row %d, %s: %s\n", rowError.Row, rowError.Column, rowError.Message)
+		} else {
+			fmt.Printf("row %d: %s\n", rowError.Row, rowError.Message)
+		}
+	}
+
+	switch {
+	case len(report.Errors) > 0:
+		return fmt.Errorf("%d errors in %d rows, nothing imported", len(report.Errors), report.Rows)
+	case report.DryRun:
+		logrus.Infof("%d rows are valid, nothing imported (dry run)", report.Rows)
+	default:
+		logrus.Infof("Imported %d subscriptions", report.Imported)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,7 +10,12 @@ import (
 	"emtest/api-service/config"
 	"emtest/api-service/db"
 	handlers "emtest/api-service/handlers"
+	"emtest/api-service/health"
+	"emtest/api-service/metrics"
 	"emtest/api-service/middleware"
+	"emtest/api-service/problem"
+	"emtest/api-service/requestid"
+	"emtest/api-service/tracing"
 
 	_ "emtest/docs"
 
@@ -33,42 +40,121 @@ func main() {
 		logrus.Fatalf("Failed to load config: %v", err)
 	}
 
+	if err := configureLogging(cfg.Logging); err != nil {
+		logrus.Fatalf("Invalid logging config: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.Database, os.Args[2:]); err != nil {
+			logrus.Fatalf("Migration failed: %s", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(cfg.Database, os.Args[2:]); err != nil {
+			logrus.Fatalf("Import failed: %s", err)
+		}
+		return
+	}
+
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			logrus.Error(err)
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			status := http.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			if status >= http.StatusInternalServerError {
+				logrus.Error(err)
+			}
+			return problem.New(status, http.StatusText(status), err.Error()).Send(c)
 		},
 	})
 
-	err = db.InitDB(cfg.Database)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logrus.Fatalf("Failed to set up tracing: %s", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.Errorf("Failed to flush traces: %s", err)
+		}
+	}()
+
+	appMetrics := metrics.New()
+
+	store, err := db.NewStore(cfg.Database, appMetrics.GormPlugin(), tracing.GormPlugin())
 	if err != nil {
 		logrus.Fatalf("Failed to init database connection: %s", err)
 	}
 
-	app.Use(cors.New())
-	app.Use(middleware.Logger(logrus.StandardLogger()))
+	subscriptionMetrics := metrics.NewSubscriptionCollector(store, store)
+	if err := appMetrics.Register(subscriptionMetrics); err != nil {
+		logrus.Fatalf("Failed to register metrics: %s", err)
+	}
+	go subscriptionMetrics.Run(context.Background(), cfg.Metrics.CostRefreshInterval)
 
-	app.Get("/swagger/*", swagger.HandlerDefault)
+	go db.RunPurge(context.Background(), store, cfg.SoftDelete)
 
-	v1 := app.Group("/api/v1")
+	h := handlers.New(store, store, store, *cfg)
 
-	v1.Post("/subscriptions", handlers.CreateSubscription)
-	v1.Get("/subscriptions", handlers.GetSubscriptions)
-	v1.Put("/subscriptions", handlers.UpdateSubscription)
-	v1.Delete("/subscriptions", handlers.DeleteSubscription)
+	requestLogger, err := middleware.Logger(logrus.StandardLogger(), cfg.Logging)
+	if err != nil {
+		logrus.Fatalf("Invalid logging config: %v", err)
+	}
+
+	app.Use(cors.New(cors.Config{ExposeHeaders: requestid.Header}))
+	app.Use(middleware.RequestID())
+	app.Use(requestLogger)
+	app.Use(appMetrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(middleware.Actor(cfg.Admin.Token, cfg.Gateway.Secret))
+
+	app.Get("/swagger/*", swagger.HandlerDefault)
+	app.Get("/metrics", appMetrics.Handler())
+
+	probes := health.New(cfg.Health.Timeout)
+	if checker, ok := store.(db.HealthChecker); ok {
+		probes.Add("database", checker.Ping)
+		probes.Add("migrations", checker.CheckSchema)
+	}
+	app.Get("/healthz", probes.Liveness)
+	app.Get("/readyz", probes.Readiness)
 
-	v1.Get("/subscriptions/calculate", handlers.CalculateTotalCost)
+	handlers.RegisterRoutes(app, h, *cfg)
 
 	logrus.Info("===============> Subscription CRUDL api <===============")
 	logrus.Info("=> Project: " + "smth")
 	logrus.Info("=> Host: " + cfg.Server.Host)
 	logrus.Info(fmt.Sprintf("=> Port: %d", cfg.Server.Port))
 	logrus.Info("=> Swagger: " + "/swagger")
+	logrus.Info("=> Metrics: " + "/metrics")
+	logrus.Info("=> Health: " + "/healthz, /readyz")
 
 	if err := app.Listen(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)); err != nil {
 		logrus.Fatalf("Failed to start server: %v", err)
 	}
 
 }
+
+// configureLogging applies the log level and format of cfg to the standard
+// logger.
+func configureLogging(cfg config.Logging) error {
+	if cfg.Level != "" {
+		level, err := logrus.ParseLevel(cfg.Level)
+		if err != nil {
+			return err
+		}
+		logrus.SetLevel(level)
+	}
+
+	switch cfg.Format {
+	case "", "text":
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("logging.format must be text or json, got %q", cfg.Format)
+	}
+	return nil
+}
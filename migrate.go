@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"emtest/api-service/config"
+	"emtest/api-service/db"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: app migrate up | down [steps] | status"
+
+// runMigrate implements the migrate subcommand: up applies every pending
+// migration, down rolls back the last one (or the given number of them) and
+// status lists the migrations with the time they were applied.
+func runMigrate(database config.Database, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+
+	conn, err := db.Connect(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			logrus.Infof("Applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			logrus.Info("Schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number: %s", migrateUsage)
+			}
+		}
+
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			logrus.Infof("Rolled back %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			logrus.Info("Nothing to roll back")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, applied)
+		}
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}